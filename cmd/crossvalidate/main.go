@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tophers42/go-naivebayes/naivebayes"
+)
+
+// parseList splits a comma-delimited flag value and parses each item.
+func parseList(value string, parse func(string) error) {
+	if value == "" {
+		return
+	}
+	for _, item := range strings.Split(value, ",") {
+		err := parse(strings.TrimSpace(item))
+		if err != nil {
+			log.Fatalf("Invalid value %q: %v", item, err)
+		}
+	}
+}
+
+// Cross-validate every configuration in the grid on a labeled dataset and print the results.
+// The dataset has one document per line in the form "<classes>\t<text>".
+func main() {
+	dataFile := flag.String("data", "", "Labeled dataset file, one '<classes>\\t<text>' document per line")
+	folds := flag.Int("folds", 5, "Number of cross-validation folds")
+	stratified := flag.Bool("stratified", false, "Keep class proportions the same in every fold")
+	seed := flag.Int64("seed", 1, "Random seed for assigning folds")
+	smoothing := flag.String("smoothing", "", "Comma-delimited smoothing values to try")
+	lowercase := flag.String("lowercase", "", "Comma-delimited lowercase settings to try, e.g. 'false,true'")
+	stripPunctuation := flag.String("strip_punctuation", "", "Comma-delimited strip punctuation settings to try")
+	minLength := flag.String("min_length", "", "Comma-delimited minimum word lengths to try")
+	flag.Parse()
+
+	if *dataFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	grid := naivebayes.Grid{}
+	parseList(*smoothing, func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		grid.Smoothing = append(grid.Smoothing, v)
+		return err
+	})
+	parseList(*lowercase, func(s string) error {
+		v, err := strconv.ParseBool(s)
+		grid.Lowercase = append(grid.Lowercase, v)
+		return err
+	})
+	parseList(*stripPunctuation, func(s string) error {
+		v, err := strconv.ParseBool(s)
+		grid.StripPunctuation = append(grid.StripPunctuation, v)
+		return err
+	})
+	parseList(*minLength, func(s string) error {
+		v, err := strconv.Atoi(s)
+		grid.MinLength = append(grid.MinLength, v)
+		return err
+	})
+
+	file, err := os.Open(*dataFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	documents, err := naivebayes.ReadDocuments(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("Failed to read dataset %s: %v", *dataFile, err)
+	}
+
+	config := naivebayes.CrossValidationConfig{Folds: *folds, Stratified: *stratified, Seed: *seed}
+	result, err := naivebayes.GridSearch(documents, grid, config)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	for _, validation := range result.Results {
+		options, _ := json.Marshal(validation.Options)
+		fmt.Printf("accuracy: %.4f (+/- %.4f) log-loss: %.4f options: %s\n", validation.MeanAccuracy, validation.StdAccuracy, validation.Overall.LogLoss, options)
+	}
+	best, _ := json.MarshalIndent(result.Best, "", "  ")
+	fmt.Printf("best:\n%s\n", best)
+}
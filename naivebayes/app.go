@@ -9,12 +9,16 @@ package naivebayes
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -40,6 +44,43 @@ func (j JSONRequest) Param(key string) (value []string) {
 	return j.QueryParams[key]
 }
 
+// IntParam returns the first value of the given query parameter as an int,
+// or fallback if the parameter is not set.
+func (j JSONRequest) IntParam(key string, fallback int) (value int, err error) {
+	param := j.Param(key)
+	if param == nil {
+		return fallback, nil
+	}
+	value, err = strconv.Atoi(param[0])
+	if err != nil {
+		return 0, fmt.Errorf("Invalid value for %s: %s", key, param[0])
+	}
+	return value, nil
+}
+
+// FloatParam returns the first value of the given query parameter as a float64,
+// or fallback if the parameter is not set.
+func (j JSONRequest) FloatParam(key string, fallback float64) (value float64, err error) {
+	param := j.Param(key)
+	if param == nil {
+		return fallback, nil
+	}
+	value, err = strconv.ParseFloat(param[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid value for %s: %s", key, param[0])
+	}
+	return value, nil
+}
+
+// validateModelName checks the modelName path variable, if the request has one.
+func (j JSONRequest) validateModelName() (err error) {
+	modelName, ok := j.Vars["modelName"]
+	if !ok {
+		return nil
+	}
+	return ValidateModelName(modelName)
+}
+
 // JSONResponse struct
 type JSONResponse struct {
 	Error error
@@ -79,7 +120,14 @@ func (j *JSONResponse) render(w http.ResponseWriter) {
 func makeJSONHandler(JSONHandler func(*JSONRequest) *JSONResponse) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Handling %s request to: %s", r.Method, r.URL.Path)
-		jsonResponse := JSONHandler(NewJSONRequest(r))
+		request := NewJSONRequest(r)
+		var jsonResponse *JSONResponse
+		// reject unsafe model names before they reach any handler
+		if nameErr := request.validateModelName(); nameErr != nil {
+			jsonResponse = &JSONResponse{Error: nameErr, Code: http.StatusBadRequest}
+		} else {
+			jsonResponse = JSONHandler(request)
+		}
 		if jsonResponse.IsError() {
 			log.Printf("%v", jsonResponse.Error)
 		}
@@ -89,15 +137,27 @@ func makeJSONHandler(JSONHandler func(*JSONRequest) *JSONResponse) http.HandlerF
 
 // Config struct
 type Config struct {
-	ModelDir string
-	Port     string
+	ModelDir       string
+	Port           string
+	Snapshots      SnapshotPolicy
+	ReloadInterval time.Duration
+	// CacheSize, when greater than zero, loads models on demand and keeps
+	// at most CacheSize of them in memory.
+	CacheSize int
 }
 
 // NaiveBayesApp struct
 type NaiveBayesApp struct {
-	modelDir string
-	models   map[string]*Model
-	port     string
+	modelDir       string
+	models         *modelCache
+	lazy           bool
+	mu             sync.RWMutex
+	files          map[string]*modelFile
+	filesMu        sync.Mutex
+	modelLocks     sync.Map
+	port           string
+	snapshotPolicy SnapshotPolicy
+	done           chan struct{}
 }
 
 // NewNaiveBayes creates and returns new App object.
@@ -107,38 +167,113 @@ func NewNaiveBayesApp(c *Config) (app *NaiveBayesApp) {
 	// init a model storage dir
 	os.Mkdir(c.ModelDir, 0775)
 
-	app = &NaiveBayesApp{models: make(map[string]*Model), modelDir: c.ModelDir, port: c.Port}
+	app = &NaiveBayesApp{models: newModelCache(c.CacheSize), lazy: c.CacheSize > 0, files: make(map[string]*modelFile), modelDir: c.ModelDir, port: c.Port, snapshotPolicy: c.Snapshots, done: make(chan struct{})}
 
 	err := app.loadAllModels()
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
+
+	// pick up models changed on disk by other processes
+	if c.ReloadInterval > 0 {
+		go app.watchModels(c.ReloadInterval)
+	}
 	return app
 }
 
-// modelPath returns the storage path for a given modelName
+// modelPath returns the storage path for a given modelName.
+// The name is escaped so it can never refer to a file outside app.modelDir.
 func (app *NaiveBayesApp) modelPath(modelName string) (path string) {
-	return app.modelDir + "/" + modelName + ".json"
+	return app.modelDir + "/" + url.PathEscape(modelName) + ".json"
 }
 
 // loadAllModels loads all the models found in app.modelDir into app.models.
+// When the app loads models lazily only the state of the files is recorded.
 func (app *NaiveBayesApp) loadAllModels() (err error) {
-	files, err := ioutil.ReadDir(app.modelDir)
+	_, err = app.reloadModels()
+	return err
+}
+
+// loadModel reads the model with the given name from app.modelDir.
+// Models that are not internally consistent are rejected.
+func (app *NaiveBayesApp) loadModel(modelName string) (model *Model, err error) {
+	model = &Model{}
+	err = LoadFromFile(app.modelPath(modelName), model, json.Unmarshal)
 	if err != nil {
-		log.Printf("Failed to load all models from dir: '%s' with error: '%s'", app.modelDir, err)
-		return err
+		return nil, err
 	}
-	for _, file := range files {
-		model := &Model{}
-		err = LoadFromFile(app.modelDir+"/"+file.Name(), model, json.Unmarshal)
-		if err != nil {
-			log.Printf("Failed to load model from file: '%s' with error: '%s'", file.Name(), err)
-		} else {
-			log.Printf("Loaded model: %s from file.", model.Name)
-			app.models[model.Name] = model
+	if err = model.Validate(); err != nil {
+		return nil, fmt.Errorf("Failed to load file %s: %v", app.modelPath(modelName), err)
+	}
+	return model, nil
+}
+
+// modelLock returns the lock that makes loading the named model from disk and saving
+// it take turns, so a model is never read while a newer copy is being saved.
+func (app *NaiveBayesApp) modelLock(modelName string) *sync.Mutex {
+	lock, _ := app.modelLocks.LoadOrStore(modelName, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// getModel returns the model with the given name, loading it into the cache
+// on a miss when the app loads models lazily. A model that is already cached is
+// never replaced by a load.
+func (app *NaiveBayesApp) getModel(modelName string) (model *Model, ok bool) {
+	model, ok = app.models.get(modelName)
+	if ok || !app.lazy {
+		return model, ok
+	}
+	lock := app.modelLock(modelName)
+	lock.Lock()
+	defer lock.Unlock()
+	model, err := app.loadModel(modelName)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to load model: '%s' with error: '%s'", modelName, err)
 		}
+		return nil, false
 	}
-	return nil
+	// another request may have loaded and changed the model in the meantime
+	model, added := app.models.addIfAbsent(model)
+	if added {
+		log.Printf("Loaded model: %s from file.", model.Name)
+	}
+	return model, true
+}
+
+// modelSummaries returns summaries of all available models. When the app loads
+// models lazily the summaries recorded for the model files are used, so listing
+// models reads nothing from disk.
+func (app *NaiveBayesApp) modelSummaries() (summaries []*ModelSummary) {
+	summaries = []*ModelSummary{}
+	if !app.lazy {
+		for _, modelName := range app.models.names() {
+			if model, ok := app.models.get(modelName); ok {
+				summaries = append(summaries, model.Summary())
+			}
+		}
+		return summaries
+	}
+	app.filesMu.Lock()
+	defer app.filesMu.Unlock()
+	for _, state := range app.files {
+		if state.summary != nil {
+			summaries = append(summaries, state.summary)
+		}
+	}
+	return summaries
+}
+
+// peekModel returns the model with the given name without changing what is cached.
+func (app *NaiveBayesApp) peekModel(modelName string) (model *Model, ok bool) {
+	if app.models.contains(modelName) {
+		return app.models.get(modelName)
+	}
+	if !app.lazy {
+		return nil, false
+	}
+	model, err := app.loadModel(modelName)
+	return model, err == nil
 }
 
 // StartServer starts the server listening on the port defined by the app object.
@@ -155,9 +290,25 @@ func (app *NaiveBayesApp) Handlers() (router *mux.Router) {
 	router = mux.NewRouter()
 	router.HandleFunc("/model", makeJSONHandler(app.createModel)).Methods("POST")
 	router.HandleFunc("/models", makeJSONHandler(app.listModels)).Methods("GET")
+	router.HandleFunc("/models/merge", makeJSONHandler(app.mergeModels)).Methods("POST")
 	router.HandleFunc("/model/{modelName}", makeJSONHandler(app.viewModel)).Methods("GET")
+	router.HandleFunc("/model/{modelName}", makeJSONHandler(app.patchModel)).Methods("PATCH")
 	router.HandleFunc("/model/{modelName}/train", makeJSONHandler(app.trainModel)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/untrain", makeJSONHandler(app.untrainModel)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/decay", makeJSONHandler(app.decayModel)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/prune", makeJSONHandler(app.pruneModel)).Methods("POST")
 	router.HandleFunc("/model/{modelName}/predict", makeJSONHandler(app.predictModel)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/explain", makeJSONHandler(app.explainModel)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/evaluate", makeJSONHandler(app.evaluateModel)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/calibrate", makeJSONHandler(app.calibrateModel)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/priors", makeJSONHandler(app.viewModelPriors)).Methods("GET")
+	router.HandleFunc("/model/{modelName}/priors", makeJSONHandler(app.setModelPriors)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/class/{className}/words", makeJSONHandler(app.classWords)).Methods("GET")
+	router.HandleFunc("/model/{modelName}/snapshots", makeJSONHandler(app.createModelSnapshot)).Methods("POST")
+	router.HandleFunc("/model/{modelName}/snapshots", makeJSONHandler(app.listModelSnapshots)).Methods("GET")
+	router.HandleFunc("/model/{modelName}/snapshots/{snapshot}/restore", makeJSONHandler(app.restoreModelSnapshot)).Methods("POST")
+	router.HandleFunc("/admin/reload", makeJSONHandler(app.reloadAllModels)).Methods("POST")
+	router.HandleFunc("/admin/cache", makeJSONHandler(app.cacheStats)).Methods("GET")
 	return router
 }
 
@@ -166,8 +317,10 @@ func (app *NaiveBayesApp) Handlers() (router *mux.Router) {
 */
 
 /*
-   createModel creates and saves a new model from the json payload
+   createModel creates and saves a new model from the json payload.
+   Models that are not internally consistent are rejected with the list of problems.
    * POST model - Create a new model
+   * POST model?recompute=1 - Rebuild the model's totals and vocabulary before validating it
 */
 func (app *NaiveBayesApp) createModel(request *JSONRequest) *JSONResponse {
 	model := &Model{}
@@ -175,51 +328,102 @@ func (app *NaiveBayesApp) createModel(request *JSONRequest) *JSONResponse {
 	if unmarshalErr != nil {
 		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
 	}
+	if request.Param("recompute") != nil {
+		model.Recompute()
+	}
+	if validateErr := model.Validate(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
+	if model.Metadata.Created.IsZero() {
+		model.Metadata.Created = now()
+		model.Metadata.Updated = model.Metadata.Created
+	}
 
-	_, exists := app.models[model.Name]
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	_, exists := app.getModel(model.Name)
 
 	if exists && request.Param("overwrite") == nil {
 		return &JSONResponse{Error: fmt.Errorf("Could not create new model. Model %s already exists.", model.Name), Code: http.StatusConflict}
 	}
 
-	saveErr := SaveToFile(app.modelPath(model.Name), model, json.Marshal)
+	saveErr := app.saveModel(model)
 	if saveErr != nil {
 		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
 	}
 
-	app.models[model.Name] = model
+	app.models.add(model)
 
-	return &JSONResponse{Data: model, Code: http.StatusOK}
+	return &JSONResponse{Data: model.Copy(), Code: http.StatusOK}
 }
 
 /*
    viewModel displays a model in JSON form.
    * GET model/<name> - view model
+   * GET model/<name>?summary=1 - view a summary of the model without its word counts
+   * GET model/<name>?fields=Name,Classes - view only the given fields
 */
 func (app *NaiveBayesApp) viewModel(request *JSONRequest) *JSONResponse {
 	modelName := request.PathVar("modelName")
-	model, ok := app.models[modelName]
+	model, ok := app.getModel(modelName)
 
 	if !ok {
 		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
 	}
 
-	return &JSONResponse{Data: model, Code: http.StatusOK}
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+	data, err := modelRepresentation(model, request.Param("summary") == nil, requestFields(request))
+	if err != nil {
+		return &JSONResponse{Error: err, Code: http.StatusBadRequest}
+	}
+	return &JSONResponse{Data: data, Code: http.StatusOK}
 }
 
 /*
-   listModels displays the list of loaded models in JSON form, in alphabetical order.
-   * GET /models - Display the list of models
+   listModels displays summaries of the available models in JSON form, in alphabetical order.
+   Models can be filtered and sorted, see parseModelQuery for the parameters.
+   * GET /models - Display the list of model summaries
+   * GET /models?full=1 - Display the list of complete models
+   * GET /models?fields=Name,Updated - Display only the given fields of each model
+   * GET /models?limit=<n>&cursor=<c> - Display a ModelPage of at most n models
 */
 func (app *NaiveBayesApp) listModels(request *JSONRequest) *JSONResponse {
-	modelList := []*Model{}
-	var sortedNames []string
-	for k := range app.models {
-		sortedNames = append(sortedNames, k)
+	query, queryErr := parseModelQuery(request)
+	if queryErr != nil {
+		return &JSONResponse{Error: queryErr, Code: http.StatusBadRequest}
 	}
-	sort.Strings(sortedNames)
-	for _, modelName := range sortedNames {
-		modelList = append(modelList, app.models[modelName])
+
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+	summaries, nextCursor, applyErr := query.Apply(app.modelSummaries())
+	if applyErr != nil {
+		return &JSONResponse{Error: applyErr, Code: http.StatusBadRequest}
+	}
+
+	full := request.Param("full") != nil
+	fields := requestFields(request)
+	modelList := []interface{}{}
+	for _, summary := range summaries {
+		var data interface{} = summary
+		var err error
+		if full {
+			// only the models on the page are loaded
+			model, ok := app.peekModel(summary.Name)
+			if !ok {
+				continue
+			}
+			data, err = modelRepresentation(model, full, fields)
+		} else if len(fields) > 0 {
+			data, err = selectFields(summary, fields)
+		}
+		if err != nil {
+			return &JSONResponse{Error: err, Code: http.StatusBadRequest}
+		}
+		modelList = append(modelList, data)
+	}
+	if query.Limit > 0 {
+		return &JSONResponse{Data: &ModelPage{Models: modelList, NextCursor: nextCursor}, Code: http.StatusOK}
 	}
 	return &JSONResponse{Data: modelList, Code: http.StatusOK}
 }
@@ -231,7 +435,9 @@ func (app *NaiveBayesApp) listModels(request *JSONRequest) *JSONResponse {
 */
 func (app *NaiveBayesApp) trainModel(request *JSONRequest) *JSONResponse {
 	modelName := request.PathVar("modelName")
-	model, ok := app.models[modelName]
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	model, ok := app.getModel(modelName)
 
 	if !ok {
 		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
@@ -242,15 +448,61 @@ func (app *NaiveBayesApp) trainModel(request *JSONRequest) *JSONResponse {
 	if unmarshalErr != nil {
 		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
 	}
+	if validateErr := observation.ValidateTraining(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
 
+	// keep the model in memory until the change is saved
+	app.models.setDirty(model.Name, true)
 	model.Train(observation)
-	saveErr := SaveToFile(app.modelPath(model.Name), model, json.Marshal)
+	saveErr := app.saveModel(model)
 	if saveErr != nil {
 		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
 	}
+	app.models.setDirty(model.Name, false)
 
 	log.Printf("Trained model: '%s' with new observation for classes: '%s'", model.Name, observation.Classes)
-	return &JSONResponse{Data: model, Code: http.StatusOK}
+	return &JSONResponse{Data: model.Copy(), Code: http.StatusOK}
+}
+
+/*
+   untrainModel removes an observation the model was trained with, such as one that
+   turned out to be mislabeled
+   * POST /model/<name/untrain - Untrains the given model with the input observation
+*/
+func (app *NaiveBayesApp) untrainModel(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	observation := &Observation{}
+	unmarshalErr := json.Unmarshal(request.Data, observation)
+	if unmarshalErr != nil {
+		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+	}
+	if validateErr := observation.ValidateTraining(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
+
+	app.models.setDirty(model.Name, true)
+	untrainErr := model.Untrain(observation)
+	if untrainErr != nil {
+		app.models.setDirty(model.Name, false)
+		return &JSONResponse{Error: untrainErr, Code: http.StatusBadRequest}
+	}
+	saveErr := app.saveModel(model)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.setDirty(model.Name, false)
+
+	log.Printf("Untrained model: '%s' with observation for classes: '%s'", model.Name, observation.Classes)
+	return &JSONResponse{Data: model.Copy(), Code: http.StatusOK}
 }
 
 /*
@@ -258,21 +510,87 @@ func (app *NaiveBayesApp) trainModel(request *JSONRequest) *JSONResponse {
    for a new observation based on the given model
    and handles form submission returning the results of the prediction in JSON
    * POST /model/<name/predict - Predicts the class for the input using the given model
+   * POST /model/<name/predict?version=<snapshot> - Predicts using a snapshot of the given model
+   * POST /model/<name/predict?top_k=<k>&min_confidence=<p>&min_margin=<m> - Returns a
+     PredictionResult with the k most probable classes, and the best class or "unknown"
+     if it is less probable than p or leads the next class by less than m
+   * POST /model/<name/predict?multi_label=1&threshold=<t> - Returns a MultiLabelResult,
+     deciding each class separately. Models with MultiLabel options always do this.
+     The threshold overrides the model's thresholds for every class.
+   * POST /model/<name/predict?calibrated=1 - Returns a CalibratedPrediction with the raw
+     probabilities and, if the model has been calibrated, the calibrated probabilities
 */
 func (app *NaiveBayesApp) predictModel(request *JSONRequest) *JSONResponse {
 	modelName := request.PathVar("modelName")
-	model, ok := app.models[modelName]
+	model, ok := app.getModel(modelName)
 
 	if !ok {
 		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
 	}
 
+	if version := request.Param("version"); version != nil {
+		snapshot, snapshotErr := app.findSnapshot(modelName, version[0])
+		if snapshotErr != nil {
+			return &JSONResponse{Error: snapshotErr, Code: http.StatusInternalServerError}
+		}
+		if snapshot == nil || snapshot.Model == nil {
+			return &JSONResponse{Error: fmt.Errorf("Snapshot not found"), Code: http.StatusNotFound}
+		}
+		model = snapshot.Model
+	}
+
 	observation := &Observation{}
 	unmarshalErr := json.Unmarshal(request.Data, observation)
 	if unmarshalErr != nil {
 		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
 	}
+	if validateErr := observation.Validate(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
+
+	if request.Param("calibrated") != nil {
+		app.mu.RLock()
+		defer app.mu.RUnlock()
+		return &JSONResponse{Data: model.PredictCalibrated(observation), Code: http.StatusOK}
+	}
+
+	if model.Options.MultiLabel != nil || request.Param("multi_label") != nil {
+		threshold, thresholdErr := request.FloatParam("threshold", 0)
+		if thresholdErr != nil || threshold < 0 || threshold > 1 {
+			return &JSONResponse{Error: fmt.Errorf("Invalid value for threshold"), Code: http.StatusBadRequest}
+		}
+		app.mu.RLock()
+		defer app.mu.RUnlock()
+		if threshold > 0 {
+			// predict with a shallow copy so the stored options are untouched
+			override := *model
+			override.Options = model.Options.Copy()
+			override.Options.MultiLabel = &MultiLabelOptions{Threshold: threshold}
+			model = &override
+		}
+		return &JSONResponse{Data: model.PredictLabels(observation), Code: http.StatusOK}
+	}
+
+	if request.Param("top_k") == nil && request.Param("min_confidence") == nil && request.Param("min_margin") == nil {
+		app.mu.RLock()
+		prediction := model.Predict(observation)
+		app.mu.RUnlock()
+		return &JSONResponse{Data: prediction, Code: http.StatusOK}
+	}
+
+	topK, topKErr := request.IntParam("top_k", 0)
+	minConfidence, minConfidenceErr := request.FloatParam("min_confidence", 0)
+	minMargin, minMarginErr := request.FloatParam("min_margin", 0)
+	for _, paramErr := range []error{topKErr, minConfidenceErr, minMarginErr} {
+		if paramErr != nil {
+			return &JSONResponse{Error: paramErr, Code: http.StatusBadRequest}
+		}
+	}
 
-	prediction := model.Predict(observation)
-	return &JSONResponse{Data: prediction, Code: http.StatusOK}
+	app.mu.RLock()
+	posterior := model.Posterior(observation)
+	app.mu.RUnlock()
+	result := &PredictionResult{Classes: posterior.TopK(topK)}
+	result.Class, result.Probability = posterior.Decide(minConfidence, minMargin)
+	return &JSONResponse{Data: result, Code: http.StatusOK}
 }
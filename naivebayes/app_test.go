@@ -31,7 +31,7 @@ func init() {
 }
 
 func cleanupModel(t *testing.T, modelName string) {
-	delete(app.models, modelName)
+	app.models.remove(modelName)
 	cleanUpErr := os.Remove(app.modelPath(modelName))
 	if cleanUpErr != nil {
 		t.Fatalf("Failed to clean up model: %s test: %v", modelName, cleanUpErr)
@@ -158,7 +158,7 @@ func TestListModels(t *testing.T) {
 	expectedModels := []*Model{emptyModel, testModel}
 
 	retrievedModels := []*Model{}
-	retrieveRequest, retrieveRequestErr := http.NewRequest(http.MethodGet, endpoint, nil)
+	retrieveRequest, retrieveRequestErr := http.NewRequest(http.MethodGet, endpoint+"?full=1", nil)
 	if retrieveRequestErr != nil {
 		t.Errorf("Failed to generate request: %v", retrieveRequestErr)
 	}
@@ -189,6 +189,8 @@ func TestTrainModel(t *testing.T) {
 	_ = unmarshalJSONResponse(t, trainRequest, http.StatusOK, &trainedModel)
 
 	trainModel.Train(testObservation)
+	// the server trained its copy at a different moment
+	trainModel.Metadata.Updated = trainedModel.Metadata.Updated
 	if !reflect.DeepEqual(&trainModel, &trainedModel) {
 		t.Errorf("Retrieved trained (%v) did not match expected model (%v).", trainedModel, trainModel)
 	}
@@ -209,3 +211,27 @@ func TestTrainModel(t *testing.T) {
 
 	cleanupModel(t, "train_model")
 }
+
+// TestResponsesCopyModel tests that handlers never return the cached model, which is
+// encoded after app.mu is released and could be trained at the same time
+func TestResponsesCopyModel(t *testing.T) {
+	copyModelJSON, _ := json.Marshal(NewModel("copy_model"))
+	createResponse := app.createModel(&JSONRequest{Data: copyModelJSON})
+	model, _ := app.getModel("copy_model")
+	if createResponse.Data == model {
+		t.Error("Create returned the cached model")
+	}
+
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{"testing"}, "copy test"))
+	vars := map[string]string{"modelName": "copy_model"}
+	if response := app.trainModel(&JSONRequest{Vars: vars, Data: observationJSON}); response.Data == model {
+		t.Error("Train returned the cached model")
+	}
+	if response := app.viewModel(&JSONRequest{Vars: vars}); response.Data == model {
+		t.Error("View returned the cached model")
+	}
+	if response := app.untrainModel(&JSONRequest{Vars: vars, Data: observationJSON}); response.Code != http.StatusOK || response.Data == model {
+		t.Errorf("Untrain returned the cached model. Got: %v", response)
+	}
+	cleanupModel(t, "copy_model")
+}
@@ -0,0 +1,171 @@
+package naivebayes
+
+import (
+	"container/list"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// CacheStats struct.
+// Counters describing how well the in-memory model cache is performing.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Loaded    int
+	Capacity  int
+}
+
+// cacheEntry is a model held by the cache. Dirty models have changes that are not
+// yet saved to disk and are never evicted.
+type cacheEntry struct {
+	model *Model
+	dirty bool
+}
+
+/*
+	modelCache holds models in memory, keyed by name.
+	When capacity is greater than zero the least recently used clean models are
+	evicted to keep at most capacity models loaded. A capacity of zero never evicts.
+*/
+type modelCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+	counters CacheStats
+}
+
+// newModelCache creates an empty cache holding at most capacity models.
+func newModelCache(capacity int) *modelCache {
+	return &modelCache{capacity: capacity, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// get returns the cached model with the given name, marking it as recently used.
+func (c *modelCache) get(name string) (model *Model, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[name]
+	if !ok {
+		c.counters.Misses++
+		return nil, false
+	}
+	c.counters.Hits++
+	c.lru.MoveToFront(element)
+	return element.Value.(*cacheEntry).model, true
+}
+
+// contains reports whether a model is cached without counting as a use.
+func (c *modelCache) contains(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[name]
+	return ok
+}
+
+// add caches the given model, replacing any cached model with the same name,
+// and evicts models if the cache is over capacity.
+func (c *modelCache) add(model *Model) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[model.Name]; ok {
+		element.Value.(*cacheEntry).model = model
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[model.Name] = c.lru.PushFront(&cacheEntry{model: model})
+	c.evict()
+}
+
+// addIfAbsent caches the given model unless a model with the same name is already
+// cached, and returns the cached model. added reports whether the given model was cached.
+func (c *modelCache) addIfAbsent(model *Model) (cached *Model, added bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[model.Name]; ok {
+		c.lru.MoveToFront(element)
+		return element.Value.(*cacheEntry).model, false
+	}
+	c.entries[model.Name] = c.lru.PushFront(&cacheEntry{model: model})
+	c.evict()
+	return model, true
+}
+
+// evict removes least recently used clean models until the cache is within capacity.
+// Must be called with c.mu held.
+func (c *modelCache) evict() {
+	if c.capacity <= 0 {
+		return
+	}
+	element := c.lru.Back()
+	for len(c.entries) > c.capacity && element != nil {
+		previous := element.Prev()
+		entry := element.Value.(*cacheEntry)
+		if !entry.dirty {
+			c.lru.Remove(element)
+			delete(c.entries, entry.model.Name)
+			c.counters.Evictions++
+			log.Printf("Evicted model: %s from cache.", entry.model.Name)
+		}
+		element = previous
+	}
+}
+
+// remove drops the model with the given name from the cache.
+func (c *modelCache) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[name]; ok {
+		c.lru.Remove(element)
+		delete(c.entries, name)
+	}
+}
+
+// setDirty flags whether the named model has unsaved changes.
+// Models that become clean may be evicted straight away.
+func (c *modelCache) setDirty(name string, dirty bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[name]; ok {
+		element.Value.(*cacheEntry).dirty = dirty
+	}
+	if !dirty {
+		c.evict()
+	}
+}
+
+// names returns the names of all cached models in alphabetical order.
+func (c *modelCache) names() (names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names = []string{}
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stats returns a copy of the cache counters.
+func (c *modelCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.counters
+	stats.Loaded = len(c.entries)
+	stats.Capacity = c.capacity
+	return stats
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   cacheStats displays the model cache counters.
+   * GET /admin/cache - Display cache hits, misses and evictions
+*/
+func (app *NaiveBayesApp) cacheStats(request *JSONRequest) *JSONResponse {
+	return &JSONResponse{Data: app.models.stats(), Code: http.StatusOK}
+}
@@ -0,0 +1,105 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestModelCache tests LRU eviction and that dirty models are kept
+func TestModelCache(t *testing.T) {
+	cache := newModelCache(2)
+	cache.add(NewModel("a"))
+	cache.add(NewModel("b"))
+	cache.get("a")
+	cache.add(NewModel("c"))
+
+	if !reflect.DeepEqual(cache.names(), []string{"a", "c"}) {
+		t.Errorf("Least recently used model was not evicted. Cached: %v", cache.names())
+	}
+
+	cache.setDirty("a", true)
+	cache.get("c")
+	cache.add(NewModel("d"))
+	if !reflect.DeepEqual(cache.names(), []string{"a", "d"}) {
+		t.Errorf("Dirty model was evicted. Cached: %v", cache.names())
+	}
+
+	cache.get("missing")
+	expected := CacheStats{Hits: 2, Misses: 1, Evictions: 2, Loaded: 2, Capacity: 2}
+	if stats := cache.stats(); stats != expected {
+		t.Errorf("Did not get expected cache stats. Expected: %v, Got: %v", expected, stats)
+	}
+
+	existing, _ := cache.get("d")
+	if cached, added := cache.addIfAbsent(NewModel("d")); added || cached != existing {
+		t.Errorf("Cached model was replaced. Added: %v", added)
+	}
+}
+
+// TestLazyLoading tests that a lazily loading app reads models on demand
+func TestLazyLoading(t *testing.T) {
+	lazyApp := NewNaiveBayesApp(&Config{ModelDir: "test_files/models", CacheSize: 1})
+
+	if names := lazyApp.models.names(); len(names) != 0 {
+		t.Errorf("Lazy app loaded models at startup: %v", names)
+	}
+	names := modelNamesOf(lazyApp.modelSummaries())
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"empty_model", "test_model"}) {
+		t.Errorf("Lazy app did not list models on disk. Got: %v", names)
+	}
+
+	if _, ok := lazyApp.getModel("test_model"); !ok {
+		t.Error("Lazy app failed to load model on demand")
+	}
+	if _, ok := lazyApp.getModel("empty_model"); !ok {
+		t.Error("Lazy app failed to load model on demand")
+	}
+	if _, ok := lazyApp.getModel("missing_model"); ok {
+		t.Error("Lazy app loaded a model that does not exist")
+	}
+
+	stats := lazyApp.models.stats()
+	if stats.Loaded != 1 || stats.Evictions != 1 || stats.Misses != 3 {
+		t.Errorf("Did not get expected cache stats. Got: %v", stats)
+	}
+}
+
+// TestLazyLoadingWaitsForSave tests that a model is not loaded while it is being saved,
+// so a load never caches a copy older than the saved model
+func TestLazyLoadingWaitsForSave(t *testing.T) {
+	modelDir, dirErr := ioutil.TempDir("", "lazy_saving")
+	if dirErr != nil {
+		t.Fatalf("Failed to create model dir: %v", dirErr)
+	}
+	defer os.RemoveAll(modelDir)
+	lazyApp := NewNaiveBayesApp(&Config{ModelDir: modelDir, CacheSize: 1})
+	model := NewModel("lazy_model")
+	if saveErr := lazyApp.saveModel(model); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+
+	// hold the lock a save takes while a newer copy is written
+	lock := lazyApp.modelLock("lazy_model")
+	lock.Lock()
+	loaded := make(chan *Model)
+	go func() {
+		model, _ := lazyApp.getModel("lazy_model")
+		loaded <- model
+	}()
+	time.Sleep(10 * time.Millisecond)
+	model.Train(NewObservationFromText([]string{"testing"}, "newer copy"))
+	if saveErr := SaveToFile(lazyApp.modelPath("lazy_model"), model, json.Marshal); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+	lock.Unlock()
+
+	if got := <-loaded; got == nil || got.ObservationCount != 1 {
+		t.Errorf("Load did not wait for the save. Got: %v", got)
+	}
+}
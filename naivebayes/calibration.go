@@ -0,0 +1,332 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// Calibration methods accepted by FitCalibration.
+const (
+	CalibrationPlatt    = "platt"
+	CalibrationIsotonic = "isotonic"
+)
+
+/*
+	Calibration struct.
+	Maps a Model's raw probabilities to calibrated ones, one class at a time.
+	Fitted on held-out observations with FitCalibration and stored with the Model.
+*/
+type Calibration struct {
+	Method  string
+	Classes map[string]*ClassCalibration
+}
+
+/*
+	ClassCalibration struct.
+	The fitted calibration of one class.
+	Platt scaling uses P = 1 / (1 + exp(A*f + B)) where f is the log-odds of the raw probability.
+	Isotonic regression interpolates between the points X (raw) and Y (calibrated).
+*/
+type ClassCalibration struct {
+	A float64   `json:",omitempty"`
+	B float64   `json:",omitempty"`
+	X []float64 `json:",omitempty"`
+	Y []float64 `json:",omitempty"`
+}
+
+// CalibratedPrediction struct.
+// A prediction before and after calibration.
+type CalibratedPrediction struct {
+	Raw        Prediction
+	Calibrated Prediction
+}
+
+// Copy returns a deep copy of the Calibration.
+func (c *Calibration) Copy() *Calibration {
+	copied := &Calibration{Method: c.Method, Classes: make(map[string]*ClassCalibration)}
+	for className, class := range c.Classes {
+		copied.Classes[className] = &ClassCalibration{A: class.A, B: class.B, X: append([]float64(nil), class.X...), Y: append([]float64(nil), class.Y...)}
+	}
+	return copied
+}
+
+// logit returns the log-odds of p, clamped away from zero and one.
+func logit(p float64) float64 {
+	p = math.Min(math.Max(p, minProbability), 1-minProbability)
+	return math.Log(p / (1 - p))
+}
+
+// rawProbabilities returns the probabilities that calibration is fitted to:
+// one-vs-rest probabilities for multi-label models, otherwise the posterior.
+func (m *Model) rawProbabilities(o *Observation) Prediction {
+	if m.Options.MultiLabel != nil {
+		return m.PredictOneVsRest(o)
+	}
+	return m.Posterior(o)
+}
+
+/*
+	FitCalibration fits a calibration for every class of the model on a held-out set
+	of labeled observations. An observation is a positive example for each of its classes.
+	The observations should not have been used to train the model.
+*/
+func FitCalibration(m *Model, observations []*Observation, method string) (calibration *Calibration, err error) {
+	if method != CalibrationPlatt && method != CalibrationIsotonic {
+		return nil, fmt.Errorf("Invalid calibration method: %s", method)
+	}
+	if len(observations) == 0 {
+		return nil, fmt.Errorf("Calibration needs at least one observation")
+	}
+
+	raw := []Prediction{}
+	for _, o := range observations {
+		raw = append(raw, m.rawProbabilities(o))
+	}
+
+	calibration = &Calibration{Method: method, Classes: make(map[string]*ClassCalibration)}
+	for className := range m.Classes {
+		scores := make([]float64, len(observations))
+		labels := make([]bool, len(observations))
+		for i, o := range observations {
+			scores[i] = raw[i][className]
+			for _, label := range o.Classes {
+				labels[i] = labels[i] || label == className
+			}
+		}
+		if method == CalibrationPlatt {
+			calibration.Classes[className] = fitPlatt(scores, labels)
+		} else {
+			calibration.Classes[className] = fitIsotonic(scores, labels)
+		}
+	}
+	return calibration, nil
+}
+
+/*
+	fitPlatt fits Platt scaling to the log-odds of the scores with Newton's method,
+	following Lin, Lin and Weng, "A note on Platt's probabilistic outputs for support
+	vector machines". Targets are smoothed to avoid overfitting small sets.
+*/
+func fitPlatt(scores []float64, labels []bool) *ClassCalibration {
+	positives, negatives := 0.0, 0.0
+	for _, label := range labels {
+		if label {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	f := make([]float64, len(scores))
+	t := make([]float64, len(scores))
+	for i := range scores {
+		f[i] = logit(scores[i])
+		t[i] = 1 / (negatives + 2)
+		if labels[i] {
+			t[i] = (positives + 1) / (positives + 2)
+		}
+	}
+
+	objective := func(a, b float64) (value float64) {
+		for i := range f {
+			fApB := f[i]*a + b
+			if fApB >= 0 {
+				value += t[i]*fApB + math.Log1p(math.Exp(-fApB))
+			} else {
+				value += (t[i]-1)*fApB + math.Log1p(math.Exp(fApB))
+			}
+		}
+		return value
+	}
+
+	a, b := 0.0, math.Log((negatives+1)/(positives+1))
+	value := objective(a, b)
+	for iteration := 0; iteration < 100; iteration++ {
+		h11, h22, h21, g1, g2 := 1e-12, 1e-12, 0.0, 0.0, 0.0
+		for i := range f {
+			fApB := f[i]*a + b
+			var p, q float64
+			if fApB >= 0 {
+				p = math.Exp(-fApB) / (1 + math.Exp(-fApB))
+				q = 1 / (1 + math.Exp(-fApB))
+			} else {
+				p = 1 / (1 + math.Exp(fApB))
+				q = math.Exp(fApB) / (1 + math.Exp(fApB))
+			}
+			d2 := p * q
+			h11 += f[i] * f[i] * d2
+			h22 += d2
+			h21 += f[i] * d2
+			d1 := t[i] - p
+			g1 += f[i] * d1
+			g2 += d1
+		}
+		if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 {
+			break
+		}
+
+		det := h11*h22 - h21*h21
+		dA := -(h22*g1 - h21*g2) / det
+		dB := -(-h21*g1 + h11*g2) / det
+		gd := g1*dA + g2*dB
+		step := 1.0
+		for ; step >= 1e-10; step /= 2 {
+			newValue := objective(a+step*dA, b+step*dB)
+			if newValue < value+0.0001*step*gd {
+				a, b, value = a+step*dA, b+step*dB, newValue
+				break
+			}
+		}
+		if step < 1e-10 {
+			break
+		}
+	}
+	return &ClassCalibration{A: a, B: b}
+}
+
+// fitIsotonic fits a non-decreasing step function to the labels with the
+// pool adjacent violators algorithm. Equal scores are always pooled together.
+// Each pooled block becomes two points.
+func fitIsotonic(scores []float64, labels []bool) *ClassCalibration {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return scores[order[i]] < scores[order[j]] })
+
+	type block struct {
+		minX, maxX, sum, weight float64
+	}
+	blocks := []block{}
+	for _, i := range order {
+		y := 0.0
+		if labels[i] {
+			y = 1
+		}
+		blocks = append(blocks, block{minX: scores[i], maxX: scores[i], sum: y, weight: 1})
+		for len(blocks) > 1 {
+			last, previous := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if previous.maxX < last.minX && previous.sum/previous.weight < last.sum/last.weight {
+				break
+			}
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = block{minX: previous.minX, maxX: last.maxX, sum: previous.sum + last.sum, weight: previous.weight + last.weight}
+		}
+	}
+
+	calibration := &ClassCalibration{X: []float64{}, Y: []float64{}}
+	for _, b := range blocks {
+		calibration.X = append(calibration.X, b.minX, b.maxX)
+		calibration.Y = append(calibration.Y, b.sum/b.weight, b.sum/b.weight)
+	}
+	return calibration
+}
+
+// apply calibrates a single raw probability.
+func (c *ClassCalibration) apply(method string, p float64) float64 {
+	if method == CalibrationPlatt {
+		return 1 / (1 + math.Exp(c.A*logit(p)+c.B))
+	}
+	if len(c.X) == 0 {
+		return p
+	}
+	if p <= c.X[0] {
+		return c.Y[0]
+	}
+	for i := 1; i < len(c.X); i++ {
+		if p <= c.X[i] {
+			if c.X[i] == c.X[i-1] {
+				return c.Y[i]
+			}
+			return c.Y[i-1] + (c.Y[i]-c.Y[i-1])*(p-c.X[i-1])/(c.X[i]-c.X[i-1])
+		}
+	}
+	return c.Y[len(c.Y)-1]
+}
+
+// Calibrate calibrates every class of a raw prediction. Classes without a fitted
+// calibration are left unchanged. When normalize is set the calibrated
+// probabilities are scaled to sum to one.
+func (c *Calibration) Calibrate(raw Prediction, normalize bool) (calibrated Prediction) {
+	calibrated = make(map[string]float64)
+	sum := 0.0
+	for className, p := range raw {
+		calibrated[className] = p
+		if class, ok := c.Classes[className]; ok {
+			calibrated[className] = class.apply(c.Method, p)
+		}
+		sum += calibrated[className]
+	}
+	if normalize && sum > 0 {
+		for className := range calibrated {
+			calibrated[className] /= sum
+		}
+	}
+	return calibrated
+}
+
+// PredictCalibrated returns the raw probabilities for the observation and, if the
+// Model has a Calibration, the calibrated probabilities.
+func (m *Model) PredictCalibrated(o *Observation) *CalibratedPrediction {
+	result := &CalibratedPrediction{Raw: m.rawProbabilities(o)}
+	if m.Calibration != nil {
+		result.Calibrated = m.Calibration.Calibrate(result.Raw, m.Options.MultiLabel == nil)
+	}
+	return result
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   calibrateModel fits a calibration to a json list of held-out labeled observations
+   and stores it with the model.
+   * POST /model/<name>/calibrate?method=platt|isotonic - Fit and save a calibration
+*/
+func (app *NaiveBayesApp) calibrateModel(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	method := CalibrationPlatt
+	if param := request.Param("method"); param != nil {
+		method = param[0]
+	}
+
+	observations := []*Observation{}
+	unmarshalErr := json.Unmarshal(request.Data, &observations)
+	if unmarshalErr != nil {
+		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+	}
+	for _, observation := range observations {
+		if validateErr := observation.ValidateTraining(); validateErr != nil {
+			return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+		}
+	}
+
+	calibration, err := FitCalibration(model, observations, method)
+	if err != nil {
+		return &JSONResponse{Error: err, Code: http.StatusBadRequest}
+	}
+
+	app.models.setDirty(model.Name, true)
+	model.Calibration = calibration
+	model.Metadata.Updated = now()
+	saveErr := app.saveModel(model)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.setDirty(model.Name, false)
+
+	log.Printf("Calibrated model: '%s' with %s calibration on %d observations", model.Name, method, len(observations))
+	return &JSONResponse{Data: calibration, Code: http.StatusOK}
+}
@@ -0,0 +1,110 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// TestFitCalibration tests that both methods move overconfident probabilities
+// towards the held-out frequencies
+func TestFitCalibration(t *testing.T) {
+	model := NewModel("calibration_model")
+	model.Train(NewObservationFromText([]string{"spam"}, "win money now"))
+	model.Train(NewObservationFromText([]string{"ham"}, "lunch meeting now"))
+
+	heldOut := []*Observation{}
+	for i := 0; i < 10; i++ {
+		heldOut = append(heldOut, NewObservationFromText([]string{"spam"}, "money"))
+	}
+	for i := 0; i < 10; i++ {
+		heldOut = append(heldOut, NewObservationFromText([]string{"ham"}, "money"))
+	}
+
+	observation := NewObservationFromText([]string{}, "money")
+	raw := model.Posterior(observation)["spam"]
+	for _, method := range []string{CalibrationPlatt, CalibrationIsotonic} {
+		calibration, err := FitCalibration(model, heldOut, method)
+		if err != nil {
+			t.Fatalf("Failed to fit %s calibration: %v", method, err)
+		}
+		model.Calibration = calibration
+		result := model.PredictCalibrated(observation)
+		if result.Raw["spam"] != raw {
+			t.Errorf("Raw %s probabilities were changed. Got: %v", method, result.Raw)
+		}
+		if math.Abs(result.Calibrated["spam"]-0.5) > 0.05 || math.Abs(result.Calibrated["spam"]+result.Calibrated["ham"]-1) > 1e-12 {
+			t.Errorf("Did not get expected %s calibration. Raw: %f, Got: %v", method, raw, result.Calibrated)
+		}
+	}
+
+	if _, err := FitCalibration(model, heldOut, "magic"); err == nil {
+		t.Error("Unknown calibration method was accepted")
+	}
+}
+
+// TestIsotonicCalibration tests that the fitted function is monotonic
+func TestIsotonicCalibration(t *testing.T) {
+	calibration := fitIsotonic([]float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6}, []bool{false, true, false, false, true, true})
+	expected := map[float64]float64{0: 0, 0.1: 0, 0.2: 1.0 / 3, 0.35: 1.0 / 3, 0.6: 1, 0.9: 1}
+	for p, value := range expected {
+		if got := calibration.apply(CalibrationIsotonic, p); math.Abs(got-value) > 1e-12 {
+			t.Errorf("Did not get expected calibrated value for %f. Expected: %f, Got: %f", p, value, got)
+		}
+	}
+}
+
+func TestCalibrateModel(t *testing.T) {
+	// setup
+	calibrateModel := NewModel("calibrate_model")
+	calibrateModel.Train(NewObservationFromText([]string{"class_a"}, "a a test"))
+	calibrateModel.Train(NewObservationFromText([]string{"class_b"}, "b text"))
+	calibrateModelJSON, _ := json.Marshal(calibrateModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(calibrateModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	observationsJSON, _ := json.Marshal([]*Observation{
+		NewObservationFromText([]string{"class_a"}, "a test"),
+		NewObservationFromText([]string{"class_b"}, "b"),
+		NewObservationFromText([]string{"class_b"}, "a"),
+	})
+	calibrateRequest, calibrateRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/calibrate_model/calibrate?method=isotonic", bytes.NewBuffer(observationsJSON))
+	if calibrateRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", calibrateRequestErr)
+	}
+	calibration := &Calibration{}
+	_ = unmarshalJSONResponse(t, calibrateRequest, http.StatusOK, calibration)
+	if calibration.Method != CalibrationIsotonic || len(calibration.Classes) != 2 {
+		t.Errorf("Did not receive expected calibration. Got: %v", calibration)
+	}
+
+	saved := &Model{}
+	loadErr := LoadFromFile(app.modelPath("calibrate_model"), saved, json.Unmarshal)
+	if loadErr != nil || saved.Calibration == nil {
+		t.Errorf("Calibration was not persisted. Error: %v", loadErr)
+	}
+
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{}, "a"))
+	predictRequest, predictRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/calibrate_model/predict?calibrated=1", bytes.NewBuffer(observationJSON))
+	if predictRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", predictRequestErr)
+	}
+	result := &CalibratedPrediction{}
+	_ = unmarshalJSONResponse(t, predictRequest, http.StatusOK, result)
+	if len(result.Raw) != 2 || len(result.Calibrated) != 2 {
+		t.Errorf("Did not receive raw and calibrated probabilities. Got: %v", result)
+	}
+
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/calibrate_model/calibrate?method=magic", bytes.NewBuffer(observationsJSON))
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &Calibration{})
+
+	cleanupModel(t, "calibrate_model")
+}
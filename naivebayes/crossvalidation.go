@@ -0,0 +1,215 @@
+package naivebayes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Document struct.
+// A labeled piece of text that has not been tokenized yet.
+type Document struct {
+	Classes []string
+	Text    string
+}
+
+// CrossValidationConfig struct.
+// Folds is the number of folds (k). Stratified folds keep the proportion of each
+// class the same in every fold. Seed makes the fold assignment repeatable.
+type CrossValidationConfig struct {
+	Folds      int
+	Stratified bool
+	Seed       int64
+}
+
+// CrossValidation struct.
+// The result of cross-validating a model configuration.
+// Overall pools the predictions of every fold into a single Evaluation.
+type CrossValidation struct {
+	Options      Options
+	Folds        []*Evaluation
+	Overall      *Evaluation
+	MeanAccuracy float64
+	StdAccuracy  float64
+}
+
+// ReadDocuments reads labeled documents, one per line, in the form "<classes>\t<text>".
+// Multiple classes are separated by commas. Blank lines are skipped.
+func ReadDocuments(r io.Reader) (documents []Document, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	documents = []Document{}
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("Line %d: expected <classes><tab><text>", line)
+		}
+		documents = append(documents, Document{Classes: strings.Split(strings.TrimSpace(parts[0]), ","), Text: parts[1]})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+/*
+	assignFolds splits the document indexes into config.Folds shuffled folds.
+	Stratified folds deal the documents of each class (their first class) out in turn
+	so every fold gets a share of every class.
+*/
+func assignFolds(documents []Document, config CrossValidationConfig) (folds [][]int) {
+	random := rand.New(rand.NewSource(config.Seed))
+	groups := map[string][]int{"": {}}
+	for i, document := range documents {
+		key := ""
+		if config.Stratified && len(document.Classes) > 0 {
+			key = document.Classes[0]
+		}
+		groups[key] = append(groups[key], i)
+	}
+	keys := []string{}
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	folds = make([][]int, config.Folds)
+	next := 0
+	for _, key := range keys {
+		indexes := groups[key]
+		random.Shuffle(len(indexes), func(i, j int) { indexes[i], indexes[j] = indexes[j], indexes[i] })
+		for _, index := range indexes {
+			folds[next] = append(folds[next], index)
+			next = (next + 1) % config.Folds
+		}
+	}
+	return folds
+}
+
+/*
+	CrossValidate runs k-fold cross-validation of a model with the given options.
+	For each fold a new model is trained on the other folds and evaluated on it.
+	Returns an error if there are fewer documents than folds or a document has no class.
+*/
+func CrossValidate(documents []Document, options Options, config CrossValidationConfig) (result *CrossValidation, err error) {
+	if config.Folds < 2 {
+		return nil, fmt.Errorf("Cross-validation needs at least 2 folds")
+	}
+	if len(documents) < config.Folds {
+		return nil, fmt.Errorf("Cross-validation needs at least as many documents as folds")
+	}
+	for i, document := range documents {
+		if len(document.Classes) == 0 {
+			return nil, fmt.Errorf("Document %d has no class", i)
+		}
+	}
+
+	folds := assignFolds(documents, config)
+	result = &CrossValidation{Options: options, Folds: []*Evaluation{}}
+	overall := NewEvaluator()
+	for k, fold := range folds {
+		held := make(map[int]bool)
+		for _, index := range fold {
+			held[index] = true
+		}
+
+		model := NewModel(fmt.Sprintf("fold-%d", k))
+		model.Options = options.Copy()
+		for i, document := range documents {
+			if !held[i] {
+				model.Train(model.NewObservation(document.Classes, document.Text))
+			}
+		}
+
+		evaluator := NewEvaluator()
+		for _, index := range fold {
+			document := documents[index]
+			posterior := model.Posterior(model.NewObservation(nil, document.Text))
+			evaluator.Add(document.Classes[0], posterior)
+			overall.Add(document.Classes[0], posterior)
+		}
+		evaluation := evaluator.Result()
+		result.Folds = append(result.Folds, evaluation)
+		result.MeanAccuracy += evaluation.Accuracy / float64(len(folds))
+	}
+
+	for _, evaluation := range result.Folds {
+		result.StdAccuracy += math.Pow(evaluation.Accuracy-result.MeanAccuracy, 2) / float64(len(folds))
+	}
+	result.StdAccuracy = math.Sqrt(result.StdAccuracy)
+	result.Overall = overall.Result()
+	return result, nil
+}
+
+// Grid struct.
+// The values of each option to try in a grid search. An empty list keeps the
+// zero value of that option.
+type Grid struct {
+	Smoothing        []float64
+	Lowercase        []bool
+	StripPunctuation []bool
+	MinLength        []int
+}
+
+// Options returns every combination of the grid's values.
+func (g Grid) Options() (options []Options) {
+	smoothing, lowercase, strip, minLength := g.Smoothing, g.Lowercase, g.StripPunctuation, g.MinLength
+	if len(smoothing) == 0 {
+		smoothing = []float64{0}
+	}
+	if len(lowercase) == 0 {
+		lowercase = []bool{false}
+	}
+	if len(strip) == 0 {
+		strip = []bool{false}
+	}
+	if len(minLength) == 0 {
+		minLength = []int{0}
+	}
+	options = []Options{}
+	for _, s := range smoothing {
+		for _, l := range lowercase {
+			for _, p := range strip {
+				for _, m := range minLength {
+					options = append(options, Options{Smoothing: s, Tokenizer: Tokenizer{Lowercase: l, StripPunctuation: p, MinLength: m}})
+				}
+			}
+		}
+	}
+	return options
+}
+
+// GridSearchResult struct.
+// The cross-validation of every configuration tried, and the best of them.
+type GridSearchResult struct {
+	Best    *CrossValidation
+	Results []*CrossValidation
+}
+
+/*
+	GridSearch cross-validates every combination of options in the grid.
+	The best configuration has the highest mean accuracy, with ties going to
+	the lowest log-loss.
+*/
+func GridSearch(documents []Document, grid Grid, config CrossValidationConfig) (result *GridSearchResult, err error) {
+	result = &GridSearchResult{Results: []*CrossValidation{}}
+	for _, options := range grid.Options() {
+		validation, err := CrossValidate(documents, options, config)
+		if err != nil {
+			return nil, err
+		}
+		result.Results = append(result.Results, validation)
+		if result.Best == nil || validation.MeanAccuracy > result.Best.MeanAccuracy ||
+			(validation.MeanAccuracy == result.Best.MeanAccuracy && validation.Overall.LogLoss < result.Best.Overall.LogLoss) {
+			result.Best = validation
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package naivebayes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var crossValidationDocuments = []Document{
+	{Classes: []string{"China"}, Text: "Chinese Beijing Chinese"},
+	{Classes: []string{"China"}, Text: "Chinese Chinese Shanghai"},
+	{Classes: []string{"China"}, Text: "Chinese Macao"},
+	{Classes: []string{"China"}, Text: "Beijing Shanghai Chinese"},
+	{Classes: []string{"Japan"}, Text: "Tokyo Japan Chinese"},
+	{Classes: []string{"Japan"}, Text: "Tokyo Osaka"},
+	{Classes: []string{"Japan"}, Text: "Japan Kyoto Tokyo"},
+	{Classes: []string{"Japan"}, Text: "Osaka Japan"},
+}
+
+// TestTokenizer tests the tokenizer settings
+func TestTokenizer(t *testing.T) {
+	tokenizer := Tokenizer{Lowercase: true, StripPunctuation: true, MinLength: 2, StopWords: []string{"the"}}
+	words := tokenizer.Tokenize("The  cat, sat on a MAT!")
+	if !reflect.DeepEqual(words, []string{"cat", "sat", "on", "mat"}) {
+		t.Errorf("Did not tokenize as expected. Got: %v", words)
+	}
+}
+
+// TestReadDocuments tests reading a labeled dataset
+func TestReadDocuments(t *testing.T) {
+	documents, err := ReadDocuments(strings.NewReader("a,b\tfirst text\n\nc\tsecond\n"))
+	expected := []Document{{Classes: []string{"a", "b"}, Text: "first text"}, {Classes: []string{"c"}, Text: "second"}}
+	if err != nil || !reflect.DeepEqual(documents, expected) {
+		t.Errorf("Did not read expected documents. Got: %v, Error: %v", documents, err)
+	}
+
+	if _, err := ReadDocuments(strings.NewReader("a\tfine\nno tab here\n")); err == nil || !strings.Contains(err.Error(), "Line 2") {
+		t.Errorf("Did not report the bad line. Got: %v", err)
+	}
+}
+
+// TestCrossValidate tests stratified folds and pooled results
+func TestCrossValidate(t *testing.T) {
+	config := CrossValidationConfig{Folds: 4, Stratified: true, Seed: 42}
+	for _, fold := range assignFolds(crossValidationDocuments, config) {
+		china := 0
+		for _, index := range fold {
+			if crossValidationDocuments[index].Classes[0] == "China" {
+				china++
+			}
+		}
+		if len(fold) != 2 || china != 1 {
+			t.Errorf("Stratified fold does not have one document of each class: %v", fold)
+		}
+	}
+
+	result, err := CrossValidate(crossValidationDocuments, Options{}, config)
+	if err != nil {
+		t.Fatalf("Cross-validation failed: %v", err)
+	}
+	if len(result.Folds) != 4 || result.Overall.Observations != 8 {
+		t.Errorf("Did not evaluate every document once. Got: %v", result)
+	}
+
+	again, _ := CrossValidate(crossValidationDocuments, Options{}, config)
+	if again.MeanAccuracy != result.MeanAccuracy || again.Overall.LogLoss != result.Overall.LogLoss {
+		t.Error("Cross-validation with the same seed was not repeatable")
+	}
+
+	if _, err := CrossValidate(crossValidationDocuments[:1], Options{}, config); err == nil {
+		t.Error("Cross-validation with fewer documents than folds did not return an error")
+	}
+}
+
+// TestGridSearch tests trying every combination of options
+func TestGridSearch(t *testing.T) {
+	grid := Grid{Smoothing: []float64{0.1, 1}, Lowercase: []bool{false, true}}
+	result, err := GridSearch(crossValidationDocuments, grid, CrossValidationConfig{Folds: 2, Seed: 1})
+	if err != nil {
+		t.Fatalf("Grid search failed: %v", err)
+	}
+	if len(result.Results) != 4 {
+		t.Errorf("Did not try every configuration. Got: %d", len(result.Results))
+	}
+	for _, validation := range result.Results {
+		if validation.MeanAccuracy > result.Best.MeanAccuracy {
+			t.Errorf("Best configuration (%v) was not the most accurate (%v)", result.Best.Options, validation.Options)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Duration type.
+// A time.Duration that is written to json as a string such as "168h".
+// Numbers of nanoseconds are also accepted.
+type Duration time.Duration
+
+// MarshalJSON writes the Duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads a Duration from a string or a number of nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) (err error) {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		parsed, parseErr := time.ParseDuration(text)
+		if parseErr != nil {
+			return fmt.Errorf("Invalid duration: %s", text)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	var nanoseconds int64
+	if json.Unmarshal(data, &nanoseconds) != nil {
+		return fmt.Errorf("Invalid duration: %s", data)
+	}
+	*d = Duration(nanoseconds)
+	return nil
+}
+
+/*
+	DecayOptions struct.
+	Makes older training fade so recent observations dominate.
+	Every count is halved for each HalfLife that passes; the decay is applied before each
+	observation is trained, or on request. Words whose counts fall below MinCount are
+	forgotten. Decayed is when counts were last decayed.
+	Untrain removes an observation at its full weight, so it should only be used for
+	observations trained since the last decay.
+*/
+type DecayOptions struct {
+	HalfLife Duration
+	MinCount float64 `json:",omitempty"`
+	Decayed  time.Time
+}
+
+// Copy returns a copy of the DecayOptions.
+func (o *DecayOptions) Copy() *DecayOptions {
+	c := *o
+	return &c
+}
+
+// scaleCounts multiplies every count of the Model by factor.
+func (m *Model) scaleCounts(factor float64) {
+	for _, class := range m.Classes {
+		class.ObservationCount *= factor
+		class.TotalCount *= factor
+		for word := range class.WordCounts {
+			class.WordCounts[word] *= factor
+		}
+		for word := range class.DocumentCounts {
+			class.DocumentCounts[word] *= factor
+		}
+	}
+	m.ObservationCount *= factor
+}
+
+// pruneWords removes words that keep returns false for from every class, and from
+// the Vocabulary once no class counts them.
+func (m *Model) pruneWords(keep func(class *Class, word string) bool) (removed int) {
+	for _, class := range m.Classes {
+		for word, count := range class.WordCounts {
+			if !keep(class, word) {
+				delete(class.WordCounts, word)
+				delete(class.DocumentCounts, word)
+				class.TotalCount, _ = subtractCount(class.TotalCount, count)
+			}
+		}
+	}
+	for word := range m.Vocabulary {
+		counted := false
+		for _, class := range m.Classes {
+			_, ok := class.WordCounts[word]
+			counted = counted || ok
+		}
+		if !counted {
+			delete(m.Vocabulary, word)
+			removed++
+		}
+	}
+	return removed
+}
+
+/*
+	Decay fades the Model's counts for the time passed between the last decay and at.
+	Does nothing if the Model has no DecayOptions. The first call only records the time,
+	since there is nothing to measure the time passed from.
+*/
+func (m *Model) Decay(at time.Time) {
+	options := m.Options.Decay
+	if options == nil || !at.After(options.Decayed) {
+		return
+	}
+	if !options.Decayed.IsZero() {
+		m.scaleCounts(math.Pow(0.5, float64(at.Sub(options.Decayed))/float64(options.HalfLife)))
+		if options.MinCount > 0 {
+			m.pruneWords(func(class *Class, word string) bool { return class.WordCounts[word] >= options.MinCount })
+		}
+		m.Metadata.Updated = at
+	}
+	options.Decayed = at
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   decayModel applies a model's decay for the time passed since it last decayed, so the
+   stored counts reflect the current time without waiting for the next observation.
+   * POST /model/<name>/decay - Decay the model's counts
+*/
+func (app *NaiveBayesApp) decayModel(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+	if model.Options.Decay == nil {
+		return &JSONResponse{Error: fmt.Errorf("Model does not decay"), Code: http.StatusBadRequest}
+	}
+
+	app.models.setDirty(model.Name, true)
+	model.Decay(now())
+	saveErr := app.saveModel(model)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.setDirty(model.Name, false)
+
+	log.Printf("Decayed model: '%s'", model.Name)
+	return &JSONResponse{Data: model.Summary(), Code: http.StatusOK}
+}
@@ -0,0 +1,90 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestDecay tests that counts halve every half-life and faded words are forgotten
+func TestDecay(t *testing.T) {
+	start := time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC)
+	model := NewModel("decay")
+	model.Options.Decay = &DecayOptions{HalfLife: Duration(7 * 24 * time.Hour), MinCount: 0.3}
+	model.Train(NewObservationFromText([]string{"spam"}, "old old scam"))
+	model.Options.Decay.Decayed = start
+
+	model.Decay(start.Add(7 * 24 * time.Hour))
+	spam := model.Classes["spam"]
+	if spam.ObservationCount != 0.5 || spam.WordCounts["old"] != 1 || spam.WordCounts["scam"] != 0.5 || spam.TotalCount != 1.5 {
+		t.Errorf("Counts were not halved after one half-life. Got: %v", spam)
+	}
+
+	model.Decay(start.Add(14 * 24 * time.Hour))
+	if _, ok := spam.WordCounts["scam"]; ok || spam.WordCounts["old"] != 0.5 || spam.TotalCount != 0.5 {
+		t.Errorf("Faded word was not forgotten. Got: %v", spam)
+	}
+	if _, ok := model.Vocabulary["scam"]; ok {
+		t.Error("Faded word was not removed from the vocabulary")
+	}
+	if err := model.Validate(); err != nil {
+		t.Errorf("Decayed model failed validation: %v", err)
+	}
+
+	model.Decay(start)
+	if spam.WordCounts["old"] != 0.5 {
+		t.Errorf("Decaying to an earlier time changed the counts. Got: %v", spam)
+	}
+}
+
+// TestDurationJSON tests that durations are read from strings and numbers
+func TestDurationJSON(t *testing.T) {
+	options := &DecayOptions{}
+	if err := json.Unmarshal([]byte(`{"HalfLife": "168h"}`), options); err != nil || time.Duration(options.HalfLife) != 168*time.Hour {
+		t.Errorf("Did not read duration string. Got: %v, Error: %v", options.HalfLife, err)
+	}
+	if err := json.Unmarshal([]byte(`{"HalfLife": 1000}`), options); err != nil || options.HalfLife != 1000 {
+		t.Errorf("Did not read duration number. Got: %v, Error: %v", options.HalfLife, err)
+	}
+	if err := json.Unmarshal([]byte(`{"HalfLife": "weekly"}`), options); err == nil {
+		t.Error("Invalid duration was accepted")
+	}
+	data, _ := json.Marshal(&DecayOptions{HalfLife: Duration(time.Hour)})
+	if !bytes.Contains(data, []byte(`"HalfLife":"1h0m0s"`)) {
+		t.Errorf("Did not write duration string. Got: %s", data)
+	}
+}
+
+func TestDecayModel(t *testing.T) {
+	// setup
+	decayModel := NewModel("decay_model")
+	decayModel.Options.Decay = &DecayOptions{HalfLife: Duration(time.Hour)}
+	decayModel.Train(NewObservationFromText([]string{"class_a"}, "a a test"))
+	decayModel.Options.Decay.Decayed = now().Add(-2 * time.Hour)
+	decayModelJSON, _ := json.Marshal(decayModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(decayModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	decayRequest, decayRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/decay_model/decay", nil)
+	if decayRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", decayRequestErr)
+	}
+	summary := &ModelSummary{}
+	_ = unmarshalJSONResponse(t, decayRequest, http.StatusOK, summary)
+	if summary.ObservationCount > 0.26 || summary.ObservationCount < 0.24 {
+		t.Errorf("Model was not decayed by two half-lives. Got: %v", summary)
+	}
+
+	noDecayRequest, noDecayRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/decay", nil)
+	if noDecayRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", noDecayRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, noDecayRequest, http.StatusBadRequest, &ModelSummary{})
+
+	cleanupModel(t, "decay_model")
+}
@@ -0,0 +1,188 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// minProbability stands in for a zero probability when calculating log-loss.
+const minProbability = 1e-15
+
+// ClassMetrics struct.
+// Precision, recall and F1 score for a single class.
+// Support is the number of evaluated observations that belong to the class.
+type ClassMetrics struct {
+	Class     string
+	Precision float64
+	Recall    float64
+	F1        float64
+	Support   int
+}
+
+/*
+	Evaluation struct.
+	Classification metrics for a model over a labeled set of observations.
+	ConfusionMatrix maps each actual class to the number of times each class was predicted for it.
+	Macro averages weigh every class equally, micro averages weigh every observation equally.
+*/
+type Evaluation struct {
+	Observations    int
+	Accuracy        float64
+	LogLoss         float64
+	Classes         []ClassMetrics
+	MacroPrecision  float64
+	MacroRecall     float64
+	MacroF1         float64
+	MicroPrecision  float64
+	MicroRecall     float64
+	MicroF1         float64
+	ConfusionMatrix map[string]map[string]int
+}
+
+// Evaluator accumulates predictions one at a time and calculates an Evaluation.
+type Evaluator struct {
+	observations int
+	logLoss      float64
+	confusion    map[string]map[string]int
+}
+
+// NewEvaluator creates an Evaluator with no predictions.
+func NewEvaluator() *Evaluator {
+	return &Evaluator{confusion: make(map[string]map[string]int)}
+}
+
+// Add records the posterior prediction made for an observation of the actual class.
+func (e *Evaluator) Add(actual string, posterior Prediction) {
+	predicted, _ := posterior.BestFit()
+	if e.confusion[actual] == nil {
+		e.confusion[actual] = make(map[string]int)
+	}
+	e.confusion[actual][predicted]++
+	e.observations++
+	e.logLoss -= math.Log(math.Max(posterior[actual], minProbability))
+}
+
+// f1 is the harmonic mean of precision and recall.
+func f1(precision, recall float64) float64 {
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
+
+// ratio divides a by b, returning zero when b is zero.
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Result calculates the metrics for the predictions added so far.
+func (e *Evaluator) Result() *Evaluation {
+	evaluation := &Evaluation{Observations: e.observations, Classes: []ClassMetrics{}, ConfusionMatrix: e.confusion}
+	if e.observations == 0 {
+		return evaluation
+	}
+	evaluation.LogLoss = e.logLoss / float64(e.observations)
+
+	classNames := make(map[string]bool)
+	for actual, predictions := range e.confusion {
+		classNames[actual] = true
+		for predicted := range predictions {
+			classNames[predicted] = true
+		}
+	}
+	sortedNames := []string{}
+	for className := range classNames {
+		sortedNames = append(sortedNames, className)
+	}
+	sort.Strings(sortedNames)
+
+	correct, predictedTotal := 0, 0
+	for _, className := range sortedNames {
+		truePositives := e.confusion[className][className]
+		predicted, support := 0, 0
+		for actual, predictions := range e.confusion {
+			predicted += predictions[className]
+			if actual == className {
+				for _, count := range predictions {
+					support += count
+				}
+			}
+		}
+		correct += truePositives
+		predictedTotal += predicted
+
+		metrics := ClassMetrics{Class: className, Precision: ratio(truePositives, predicted), Recall: ratio(truePositives, support), Support: support}
+		metrics.F1 = f1(metrics.Precision, metrics.Recall)
+		evaluation.Classes = append(evaluation.Classes, metrics)
+		evaluation.MacroPrecision += metrics.Precision / float64(len(sortedNames))
+		evaluation.MacroRecall += metrics.Recall / float64(len(sortedNames))
+		evaluation.MacroF1 += metrics.F1 / float64(len(sortedNames))
+	}
+
+	evaluation.Accuracy = ratio(correct, e.observations)
+	evaluation.MicroPrecision = ratio(correct, predictedTotal)
+	evaluation.MicroRecall = ratio(correct, e.observations)
+	evaluation.MicroF1 = f1(evaluation.MicroPrecision, evaluation.MicroRecall)
+	return evaluation
+}
+
+/*
+	Evaluate predicts every observation with the model and compares the best fit
+	with the observation's first class, which is taken as its true label.
+	Returns an error if an observation has no class.
+*/
+func Evaluate(m *Model, observations []*Observation) (evaluation *Evaluation, err error) {
+	evaluator := NewEvaluator()
+	for i, o := range observations {
+		if len(o.Classes) == 0 {
+			return nil, fmt.Errorf("Observation %d has no class to evaluate against", i)
+		}
+		evaluator.Add(o.Classes[0], m.Posterior(o))
+	}
+	return evaluator.Result(), nil
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   evaluateModel measures the model against a json list of labeled observations.
+   * POST /model/<name>/evaluate - Report accuracy, per-class metrics, log-loss and a confusion matrix
+*/
+func (app *NaiveBayesApp) evaluateModel(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	observations := []*Observation{}
+	unmarshalErr := json.Unmarshal(request.Data, &observations)
+	if unmarshalErr != nil {
+		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+	}
+	for _, observation := range observations {
+		if validateErr := observation.ValidateTraining(); validateErr != nil {
+			return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+		}
+	}
+
+	app.mu.RLock()
+	evaluation, err := Evaluate(model, observations)
+	app.mu.RUnlock()
+	if err != nil {
+		return &JSONResponse{Error: err, Code: http.StatusBadRequest}
+	}
+
+	log.Printf("Evaluated model: '%s' on %d observations, accuracy: %f", modelName, evaluation.Observations, evaluation.Accuracy)
+	return &JSONResponse{Data: evaluation, Code: http.StatusOK}
+}
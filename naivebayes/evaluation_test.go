@@ -0,0 +1,66 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// TestEvaluator tests metrics against hand calculated values
+func TestEvaluator(t *testing.T) {
+	evaluator := NewEvaluator()
+	evaluator.Add("spam", Prediction{"spam": 0.8, "ham": 0.2})
+	evaluator.Add("spam", Prediction{"spam": 0.4, "ham": 0.6})
+	evaluator.Add("ham", Prediction{"spam": 0.1, "ham": 0.9})
+	evaluator.Add("ham", Prediction{"spam": 0.3, "ham": 0.7})
+	evaluation := evaluator.Result()
+
+	if evaluation.Observations != 4 || evaluation.Accuracy != 0.75 {
+		t.Errorf("Did not get expected accuracy. Got: %v", evaluation)
+	}
+	expectedLogLoss := -(math.Log(0.8) + math.Log(0.4) + math.Log(0.9) + math.Log(0.7)) / 4
+	if math.Abs(evaluation.LogLoss-expectedLogLoss) > 1e-12 {
+		t.Errorf("Did not get expected log-loss. Expected: %f, Got: %f", expectedLogLoss, evaluation.LogLoss)
+	}
+	if evaluation.ConfusionMatrix["spam"]["ham"] != 1 || evaluation.ConfusionMatrix["ham"]["ham"] != 2 {
+		t.Errorf("Did not get expected confusion matrix. Got: %v", evaluation.ConfusionMatrix)
+	}
+
+	ham, spam := evaluation.Classes[0], evaluation.Classes[1]
+	if ham.Class != "ham" || math.Abs(ham.Precision-2.0/3) > 1e-12 || ham.Recall != 1 || ham.Support != 2 {
+		t.Errorf("Did not get expected ham metrics. Got: %v", ham)
+	}
+	if spam.Class != "spam" || spam.Precision != 1 || spam.Recall != 0.5 || math.Abs(spam.F1-2.0/3) > 1e-12 {
+		t.Errorf("Did not get expected spam metrics. Got: %v", spam)
+	}
+	if math.Abs(evaluation.MacroRecall-0.75) > 1e-12 || evaluation.MicroF1 != 0.75 {
+		t.Errorf("Did not get expected averages. Got: %v", evaluation)
+	}
+}
+
+func TestEvaluateModel(t *testing.T) {
+	observations := []*Observation{
+		NewObservationFromText([]string{"class_a"}, "a a test"),
+		NewObservationFromText([]string{"class_b"}, "b text"),
+		NewObservationFromText([]string{"class_b"}, "a a a"),
+	}
+	observationsJSON, _ := json.Marshal(observations)
+	evaluateRequest, evaluateRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/evaluate", bytes.NewBuffer(observationsJSON))
+	if evaluateRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", evaluateRequestErr)
+	}
+	evaluation := &Evaluation{}
+	_ = unmarshalJSONResponse(t, evaluateRequest, http.StatusOK, evaluation)
+	if evaluation.Observations != 3 || math.Abs(evaluation.Accuracy-2.0/3) > 1e-12 || evaluation.ConfusionMatrix["class_b"]["class_a"] != 1 {
+		t.Errorf("Did not receive expected evaluation. Got: %v", evaluation)
+	}
+
+	unlabeledJSON, _ := json.Marshal([]*Observation{NewObservationFromText([]string{}, "a")})
+	unlabeledRequest, unlabeledRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/evaluate", bytes.NewBuffer(unlabeledJSON))
+	if unlabeledRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", unlabeledRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, unlabeledRequest, http.StatusBadRequest, &ValidationError{})
+}
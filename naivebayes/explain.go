@@ -0,0 +1,145 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// WordContribution struct.
+// How much one word of an observation contributed to a class's log score.
+// Evidence is the contribution relative to the average over all classes:
+// positive evidence favours the class, negative evidence counts against it.
+type WordContribution struct {
+	Word         string
+	Count        float64
+	Contribution float64
+	Evidence     float64
+}
+
+// ClassExplanation struct.
+// Breaks the log score of a class down into its prior and word contributions.
+// Words are ordered from strongest positive to strongest negative evidence.
+type ClassExplanation struct {
+	Class       string
+	Prior       float64
+	Score       float64
+	Probability float64
+	Words       []WordContribution
+}
+
+// ClassEvidence struct.
+// The strongest evidence for and against a class.
+type ClassEvidence struct {
+	Class       string
+	Prior       float64
+	Score       float64
+	Probability float64
+	Positive    []WordContribution
+	Negative    []WordContribution
+}
+
+/*
+	Explain breaks a prediction for the given observation down by class.
+	Each class's Score is its Prior plus the Contribution of every observed word,
+	the same log terms that Predict sums. Classes are ordered from most to least probable.
+	Models that hash features explain their buckets rather than the observed words,
+	and Count is the word's value after any transformations.
+*/
+func (m *Model) Explain(o *Observation) (explanations []ClassExplanation) {
+	posterior := m.Posterior(o)
+	counts := m.features(o)
+
+	// average contribution of each word over all classes
+	average := make(map[string]float64)
+	for _, class := range m.Classes {
+		for word, count := range counts {
+			average[word] += m.wordLogProbability(class, word) * count / float64(len(m.Classes))
+		}
+	}
+
+	explanations = []ClassExplanation{}
+	for _, class := range m.Classes {
+		explanation := ClassExplanation{Class: class.Name, Prior: m.classPriorProbability(class), Probability: posterior[class.Name], Words: []WordContribution{}}
+		explanation.Score = explanation.Prior
+		for word, count := range counts {
+			contribution := m.wordLogProbability(class, word) * count
+			explanation.Score += contribution
+			explanation.Words = append(explanation.Words, WordContribution{Word: word, Count: count, Contribution: contribution, Evidence: contribution - average[word]})
+		}
+		sort.Slice(explanation.Words, func(i, j int) bool {
+			if explanation.Words[i].Evidence != explanation.Words[j].Evidence {
+				return explanation.Words[i].Evidence > explanation.Words[j].Evidence
+			}
+			return explanation.Words[i].Word < explanation.Words[j].Word
+		})
+		explanations = append(explanations, explanation)
+	}
+	sort.Slice(explanations, func(i, j int) bool {
+		if explanations[i].Score != explanations[j].Score {
+			return explanations[i].Score > explanations[j].Score
+		}
+		return explanations[i].Class < explanations[j].Class
+	})
+	return explanations
+}
+
+// Evidence returns at most n words with the strongest positive evidence and at most n
+// words with the strongest negative evidence for the class, strongest first.
+func (e *ClassExplanation) Evidence(n int) *ClassEvidence {
+	evidence := &ClassEvidence{Class: e.Class, Prior: e.Prior, Score: e.Score, Probability: e.Probability, Positive: []WordContribution{}, Negative: []WordContribution{}}
+	for _, word := range e.Words {
+		if word.Evidence > 0 && len(evidence.Positive) < n {
+			evidence.Positive = append(evidence.Positive, word)
+		}
+	}
+	for i := len(e.Words) - 1; i >= 0; i-- {
+		if e.Words[i].Evidence < 0 && len(evidence.Negative) < n {
+			evidence.Negative = append(evidence.Negative, e.Words[i])
+		}
+	}
+	return evidence
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   explainModel explains a prediction for the observation in the json payload,
+   returning the top positive and negative evidence words for every class.
+   * POST /model/<name>/explain - Explain a prediction with the 10 strongest words each way
+   * POST /model/<name>/explain?top=<n> - Explain a prediction with the n strongest words each way
+*/
+func (app *NaiveBayesApp) explainModel(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	top, topErr := request.IntParam("top", 10)
+	if topErr != nil || top < 0 {
+		return &JSONResponse{Error: fmt.Errorf("Invalid value for top"), Code: http.StatusBadRequest}
+	}
+
+	observation := &Observation{}
+	unmarshalErr := json.Unmarshal(request.Data, observation)
+	if unmarshalErr != nil {
+		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+	}
+	if validateErr := observation.Validate(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
+
+	app.mu.RLock()
+	explanations := model.Explain(observation)
+	app.mu.RUnlock()
+	evidence := []*ClassEvidence{}
+	for i := range explanations {
+		evidence = append(evidence, explanations[i].Evidence(top))
+	}
+	return &JSONResponse{Data: evidence, Code: http.StatusOK}
+}
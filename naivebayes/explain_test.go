@@ -0,0 +1,64 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// TestExplain tests that explanations add up to the prediction scores
+func TestExplain(t *testing.T) {
+	model := NewModel("explain")
+	model.Train(NewObservationFromText([]string{"China"}, "Chinese Beijing Chinese"))
+	model.Train(NewObservationFromText([]string{"NotChina"}, "Tokyo Japan Chinese"))
+	observation := NewObservationFromText([]string{}, "Chinese Chinese Tokyo")
+
+	scores := model.logScores(model.features(observation))
+	explanations := model.Explain(observation)
+	if len(explanations) != 2 || explanations[0].Class != "China" {
+		t.Fatalf("Did not receive expected explanations. Got: %v", explanations)
+	}
+	for _, explanation := range explanations {
+		sum := explanation.Prior
+		for _, word := range explanation.Words {
+			sum += word.Contribution
+		}
+		if math.Abs(sum-scores[explanation.Class]) > 1e-9 || math.Abs(explanation.Score-sum) > 1e-9 {
+			t.Errorf("Explanation of %s does not add up to its score %v: %v", explanation.Class, scores[explanation.Class], explanation)
+		}
+	}
+
+	evidence := explanations[0].Evidence(5)
+	if len(evidence.Positive) != 1 || evidence.Positive[0].Word != "Chinese" {
+		t.Errorf("Did not receive expected positive evidence. Got: %v", evidence.Positive)
+	}
+	if len(evidence.Negative) != 1 || evidence.Negative[0].Word != "Tokyo" {
+		t.Errorf("Did not receive expected negative evidence. Got: %v", evidence.Negative)
+	}
+}
+
+func TestExplainModel(t *testing.T) {
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{}, "a b test"))
+	explainRequest, explainRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/explain?top=1", bytes.NewBuffer(observationJSON))
+	if explainRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", explainRequestErr)
+	}
+	evidence := []*ClassEvidence{}
+	_ = unmarshalJSONResponse(t, explainRequest, http.StatusOK, &evidence)
+	if len(evidence) != 2 {
+		t.Fatalf("Did not receive evidence for every class. Got: %v", evidence)
+	}
+	for _, classEvidence := range evidence {
+		if len(classEvidence.Positive) != 1 || len(classEvidence.Negative) != 1 {
+			t.Errorf("Did not receive expected evidence for %s. Got: %v", classEvidence.Class, classEvidence)
+		}
+	}
+
+	missingRequest, missingRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/missing_model/explain", bytes.NewBuffer(observationJSON))
+	if missingRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", missingRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, missingRequest, http.StatusNotFound, &ClassEvidence{})
+}
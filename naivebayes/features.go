@@ -0,0 +1,226 @@
+package naivebayes
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// Feature selection methods accepted by Model.FeatureScores.
+const (
+	SelectByChiSquare          = "chi_square"
+	SelectByMutualInformation  = "mutual_information"
+	SelectByDocumentFrequency  = "document_frequency"
+	defaultFeatureSelectMethod = SelectByChiSquare
+)
+
+// FeatureScore struct.
+// How useful a word is for telling the Model's classes apart.
+type FeatureScore struct {
+	Word  string
+	Score float64
+}
+
+// documentCount returns the number of observations of the class that contained the word.
+// Classes saved before document counts were tracked fall back to the word count,
+// which is the same for words that appear at most once per observation.
+func (c *Class) documentCount(word string) float64 {
+	if c.DocumentCounts != nil {
+		return c.DocumentCounts[word]
+	}
+	return math.Min(c.WordCounts[word], c.ObservationCount)
+}
+
+// contingency holds the number of observations with and without a word,
+// in and out of a class.
+type contingency struct {
+	inWith, outWith, inWithout, outWithout float64
+}
+
+// contingencyTable counts the observations of the Model by whether they are in the
+// class and whether they contain the word.
+func (m *Model) contingencyTable(class *Class, word string, documents float64) (table contingency) {
+	table.inWith = class.documentCount(word)
+	table.outWith = math.Max(documents-table.inWith, 0)
+	table.inWithout = math.Max(class.ObservationCount-table.inWith, 0)
+	table.outWithout = math.Max(m.ObservationCount-table.inWith-table.outWith-table.inWithout, 0)
+	return table
+}
+
+// chiSquare tests the independence of the word and the class.
+func (t contingency) chiSquare() float64 {
+	n := t.inWith + t.outWith + t.inWithout + t.outWithout
+	denominator := (t.inWith + t.inWithout) * (t.outWith + t.outWithout) * (t.inWith + t.outWith) * (t.inWithout + t.outWithout)
+	if denominator == 0 {
+		return 0
+	}
+	difference := t.inWith*t.outWithout - t.inWithout*t.outWith
+	return n * difference * difference / denominator
+}
+
+// mutualInformation measures how much knowing whether the word is present tells about the class.
+func (t contingency) mutualInformation() (mi float64) {
+	n := t.inWith + t.outWith + t.inWithout + t.outWithout
+	cells := []struct{ count, word, class float64 }{
+		{t.inWith, t.inWith + t.outWith, t.inWith + t.inWithout},
+		{t.outWith, t.inWith + t.outWith, t.outWith + t.outWithout},
+		{t.inWithout, t.inWithout + t.outWithout, t.inWith + t.inWithout},
+		{t.outWithout, t.inWithout + t.outWithout, t.outWith + t.outWithout},
+	}
+	for _, cell := range cells {
+		if cell.count > 0 {
+			mi += cell.count / n * math.Log2(n*cell.count/(cell.word*cell.class))
+		}
+	}
+	return mi
+}
+
+/*
+	FeatureScores scores every word in the Vocabulary, best first.
+	SelectByChiSquare and SelectByMutualInformation score each word against every class
+	and keep the highest score. SelectByDocumentFrequency counts the observations the word
+	appeared in. Words with equal scores are ordered alphabetically.
+*/
+func (m *Model) FeatureScores(method string) (scores []FeatureScore, err error) {
+	if method != SelectByChiSquare && method != SelectByMutualInformation && method != SelectByDocumentFrequency {
+		return nil, fmt.Errorf("Invalid feature selection method: %s", method)
+	}
+
+	scores = []FeatureScore{}
+	for word := range m.Vocabulary {
+		documents := 0.0
+		for _, class := range m.Classes {
+			documents += class.documentCount(word)
+		}
+		score := 0.0
+		switch method {
+		case SelectByDocumentFrequency:
+			score = documents
+		case SelectByChiSquare:
+			for _, class := range m.Classes {
+				score = math.Max(score, m.contingencyTable(class, word, documents).chiSquare())
+			}
+		case SelectByMutualInformation:
+			for _, class := range m.Classes {
+				score = math.Max(score, m.contingencyTable(class, word, documents).mutualInformation())
+			}
+		}
+		scores = append(scores, FeatureScore{Word: word, Score: score})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Word < scores[j].Word
+	})
+	return scores, nil
+}
+
+// SelectFeatures returns a copy of the Model that only keeps the top n words by the
+// given method. The Model itself is not changed.
+func (m *Model) SelectFeatures(method string, n int) (selected *Model, err error) {
+	if n < 1 {
+		return nil, fmt.Errorf("Invalid number of features: %d", n)
+	}
+	scores, err := m.FeatureScores(method)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool)
+	for i := 0; i < n && i < len(scores); i++ {
+		keep[scores[i].Word] = true
+	}
+
+	selected = m.Copy()
+	selected.pruneWords(func(class *Class, word string) bool { return keep[word] })
+	for word := range selected.Vocabulary {
+		if !keep[word] {
+			delete(selected.Vocabulary, word)
+		}
+	}
+	selected.Metadata.Updated = now()
+	return selected, nil
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   pruneModel reduces a model to its most useful words.
+   * POST /model/<name>/prune?min_count=<c>&min_documents=<d>&max_document_ratio=<r> -
+     Remove rare and overly common words and save the model
+   * POST /model/<name>/prune?top=<n>&method=chi_square|mutual_information|document_frequency -
+     Keep the top n words and save the model. Combined with the thresholds, the top n
+     words are chosen from the words that pass them.
+   * POST /model/<name>/prune?top=<n>&target=<new name> - Save the pruned model as a new model,
+     leaving the original unchanged
+*/
+func (app *NaiveBayesApp) pruneModel(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	minCount, minCountErr := request.FloatParam("min_count", 0)
+	minDocuments, minDocumentsErr := request.FloatParam("min_documents", 0)
+	maxDocumentRatio, maxDocumentRatioErr := request.FloatParam("max_document_ratio", 0)
+	for _, paramErr := range []error{minCountErr, minDocumentsErr, maxDocumentRatioErr} {
+		if paramErr != nil {
+			return &JSONResponse{Error: paramErr, Code: http.StatusBadRequest}
+		}
+	}
+	options := &PruneOptions{MinCount: minCount, MinDocuments: minDocuments, MaxDocumentRatio: maxDocumentRatio}
+	v := &ValidationError{}
+	options.validate(v, "Pruning")
+	if validateErr := v.result(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
+	if *options == (PruneOptions{}) && request.Param("top") == nil {
+		return &JSONResponse{Error: fmt.Errorf("Nothing to prune. Set top or a threshold."), Code: http.StatusBadRequest}
+	}
+
+	pruned := model.Copy()
+	pruned.PruneVocabulary(options)
+	if request.Param("top") != nil {
+		method := defaultFeatureSelectMethod
+		if param := request.Param("method"); param != nil {
+			method = param[0]
+		}
+		top, topErr := request.IntParam("top", 0)
+		if topErr != nil {
+			return &JSONResponse{Error: topErr, Code: http.StatusBadRequest}
+		}
+		var pruneErr error
+		pruned, pruneErr = pruned.SelectFeatures(method, top)
+		if pruneErr != nil {
+			return &JSONResponse{Error: pruneErr, Code: http.StatusBadRequest}
+		}
+	}
+
+	if target := request.Param("target"); target != nil {
+		if nameErr := ValidateModelName(target[0]); nameErr != nil {
+			return &JSONResponse{Error: nameErr, Code: http.StatusBadRequest}
+		}
+		if _, exists := app.getModel(target[0]); exists {
+			return &JSONResponse{Error: fmt.Errorf("Could not create new model. Model %s already exists.", target[0]), Code: http.StatusConflict}
+		}
+		pruned.Name = target[0]
+		pruned.Metadata.Created = pruned.Metadata.Updated
+	}
+
+	saveErr := app.saveModel(pruned)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.add(pruned)
+
+	log.Printf("Pruned model: '%s' to %d words, saved as: '%s'", model.Name, len(pruned.Vocabulary), pruned.Name)
+	return &JSONResponse{Data: pruned.Summary(), Code: http.StatusOK}
+}
@@ -0,0 +1,102 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func featureWords(scores []FeatureScore) (words []string) {
+	words = []string{}
+	for _, score := range scores {
+		words = append(words, score.Word)
+	}
+	return words
+}
+
+// TestFeatureScores tests each method against hand calculated values
+func TestFeatureScores(t *testing.T) {
+	model := NewModel("features")
+	model.Train(NewObservationFromText([]string{"spam"}, "win money the"))
+	model.Train(NewObservationFromText([]string{"spam"}, "win prize the"))
+	model.Train(NewObservationFromText([]string{"ham"}, "lunch the"))
+	model.Train(NewObservationFromText([]string{"ham"}, "meeting the the"))
+
+	frequency, _ := model.FeatureScores(SelectByDocumentFrequency)
+	if frequency[0].Word != "the" || frequency[0].Score != 4 || frequency[1].Word != "win" || frequency[1].Score != 2 {
+		t.Errorf("Did not get expected document frequencies. Got: %v", frequency)
+	}
+
+	// "win" is in every spam observation and no ham observation
+	chiSquare, _ := model.FeatureScores(SelectByChiSquare)
+	if chiSquare[0].Word != "win" || chiSquare[0].Score != 4 || chiSquare[len(chiSquare)-1].Word != "the" || chiSquare[len(chiSquare)-1].Score != 0 {
+		t.Errorf("Did not get expected chi-square scores. Got: %v", chiSquare)
+	}
+
+	mutualInformation, _ := model.FeatureScores(SelectByMutualInformation)
+	if mutualInformation[0].Word != "win" || math.Abs(mutualInformation[0].Score-1) > 1e-12 {
+		t.Errorf("Did not get expected mutual information. Got: %v", mutualInformation)
+	}
+
+	if _, err := model.FeatureScores("magic"); err == nil {
+		t.Error("Unknown feature selection method was accepted")
+	}
+}
+
+// TestSelectFeatures tests that a pruned copy keeps only the top words
+func TestSelectFeatures(t *testing.T) {
+	model := NewModel("features")
+	model.Train(NewObservationFromText([]string{"spam"}, "win money the"))
+	model.Train(NewObservationFromText([]string{"ham"}, "lunch the"))
+
+	selected, err := model.SelectFeatures(SelectByChiSquare, 2)
+	if err != nil {
+		t.Fatalf("Failed to select features: %v", err)
+	}
+	if !reflect.DeepEqual(selected.Vocabulary, map[string]int{"lunch": 1, "money": 1}) || selected.Classes["spam"].TotalCount != 1 {
+		t.Errorf("Did not keep the top words. Got: %v, %v", selected.Vocabulary, selected.Classes["spam"])
+	}
+	if err := selected.Validate(); err != nil {
+		t.Errorf("Pruned model failed validation: %v", err)
+	}
+	if len(model.Vocabulary) != 4 {
+		t.Error("Selecting features changed the original model")
+	}
+}
+
+func TestPruneModel(t *testing.T) {
+	pruneRequest, pruneRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/prune?top=2&method=document_frequency&target=pruned_model", nil)
+	if pruneRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", pruneRequestErr)
+	}
+	summary := &ModelSummary{}
+	_ = unmarshalJSONResponse(t, pruneRequest, http.StatusOK, summary)
+	if summary.Name != "pruned_model" || summary.VocabularySize != 2 {
+		t.Errorf("Did not receive pruned model. Got: %v", summary)
+	}
+
+	saved := &Model{}
+	loadErr := LoadFromFile(app.modelPath("pruned_model"), saved, json.Unmarshal)
+	if loadErr != nil || !reflect.DeepEqual(saved.Vocabulary, map[string]int{"test": 1, "text": 1}) {
+		t.Errorf("Pruned model was not saved. Got: %v, Error: %v", saved.Vocabulary, loadErr)
+	}
+	if original, _ := app.getModel("test_model"); len(original.Vocabulary) != 4 {
+		t.Error("Pruning to a target changed the original model")
+	}
+
+	conflictRequest, conflictRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/prune?top=2&target=pruned_model", nil)
+	if conflictRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", conflictRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, conflictRequest, http.StatusConflict, &ModelSummary{})
+
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/prune", nil)
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &ModelSummary{})
+
+	cleanupModel(t, "pruned_model")
+}
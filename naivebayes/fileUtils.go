@@ -22,12 +22,13 @@ func SaveToFile(path string, v interface{}, marshalFunc func(v interface{}) ([]b
 }
 
 // LoadFromFile loads the data from the given path using the given unmmarshalling function
-// to convert the []byte data into an interface
+// to convert the []byte data into an interface. Errors reading the file are wrapped, so a
+// missing file can be detected with errors.Is(err, os.ErrNotExist).
 func LoadFromFile(path string, v interface{}, unmarshalFunc func(data []byte, v interface{}) error) (err error) {
 
 	fileBuf, err := ioutil.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("Failed to read file %s: %v", path, err)
+		return fmt.Errorf("Failed to read file %s: %w", path, err)
 	}
 
 	err = unmarshalFunc(fileBuf, v)
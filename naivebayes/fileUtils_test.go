@@ -3,6 +3,7 @@ package naivebayes
 import (
 	"encoding/json"
 	"errors"
+	"os"
 	"reflect"
 	"testing"
 )
@@ -64,8 +65,8 @@ func TestLoadErrors(t *testing.T) {
 	load := testStruct{}
 
 	invalidPathErr := LoadFromFile("this/is/not/a/valid/path", &load, json.Unmarshal)
-	if invalidPathErr == nil {
-		t.Error("Invalid file path did not throw expected error")
+	if !errors.Is(invalidPathErr, os.ErrNotExist) {
+		t.Errorf("Invalid file path did not throw expected error. Got: %v", invalidPathErr)
 	}
 
 	failedUnmarshal := func(data []byte, v interface{}) error {
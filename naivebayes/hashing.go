@@ -0,0 +1,51 @@
+package naivebayes
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strconv"
+)
+
+/*
+	HashingOptions struct.
+	Enables the hashing trick: words are hashed into a fixed number of Buckets and the
+	Model counts buckets instead of words, so its size is bounded however many distinct
+	words it sees. Words that share a bucket are counted together.
+	The Seed changes which words share buckets. Neither can be changed once the Model
+	has been trained, since the counts would no longer match the words.
+*/
+type HashingOptions struct {
+	Buckets int
+	Seed    uint32 `json:",omitempty"`
+}
+
+// Copy returns a copy of the HashingOptions.
+func (o *HashingOptions) Copy() *HashingOptions {
+	c := *o
+	return &c
+}
+
+// Bucket returns the name of the bucket the word is counted in.
+func (o *HashingOptions) Bucket(word string) string {
+	h := fnv.New32a()
+	seed := make([]byte, 4)
+	binary.LittleEndian.PutUint32(seed, o.Seed)
+	h.Write(seed)
+	h.Write([]byte(word))
+	return strconv.FormatUint(uint64(h.Sum32()%uint32(o.Buckets)), 10)
+}
+
+// validBucket reports whether name is one of the buckets.
+func (o *HashingOptions) validBucket(name string) bool {
+	bucket, err := strconv.ParseUint(name, 10, 32)
+	return err == nil && bucket < uint64(o.Buckets) && strconv.FormatUint(bucket, 10) == name
+}
+
+// hash sums the counts of words that share a bucket.
+func (o *HashingOptions) hash(counts map[string]float64) (hashed map[string]float64) {
+	hashed = make(map[string]float64)
+	for word, count := range counts {
+		hashed[o.Bucket(word)] += count
+	}
+	return hashed
+}
@@ -0,0 +1,113 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// bucketObservation returns the observation with its words replaced by the model's buckets.
+func bucketObservation(m *Model, o *Observation) *Observation {
+	bucketed := &Observation{Classes: o.Classes, WordCounts: make(map[string]int)}
+	for bucket, count := range m.features(o) {
+		bucketed.WordCounts[bucket] = int(count)
+	}
+	return bucketed
+}
+
+// TestHashing tests that a hashed model counts buckets and predicts like the
+// same model trained on the bucket names
+func TestHashing(t *testing.T) {
+	hashing := &HashingOptions{Buckets: 8, Seed: 42}
+	hashed := NewModel("hashed")
+	hashed.Options.Hashing = hashing
+	plain := NewModel("plain")
+
+	for _, o := range []*Observation{
+		NewObservationFromText([]string{"China"}, "Chinese Beijing Chinese"),
+		NewObservationFromText([]string{"China"}, "Chinese Chinese Shanghai"),
+		NewObservationFromText([]string{"NotChina"}, "Tokyo Japan Chinese"),
+	} {
+		hashed.Train(o)
+		plain.Train(bucketObservation(hashed, o))
+	}
+	for word := range hashed.Vocabulary {
+		if !hashing.validBucket(word) {
+			t.Errorf("Vocabulary holds a word instead of a bucket: %s", word)
+		}
+	}
+	if hashed.Classes["China"].WordCounts[hashing.Bucket("Chinese")] < 4 {
+		t.Errorf("Words were not counted in their buckets. Got: %v", hashed.Classes["China"])
+	}
+
+	observation := NewObservationFromText([]string{}, "Chinese Tokyo Japan")
+	expected, got := plain.Posterior(bucketObservation(hashed, observation)), hashed.Posterior(observation)
+	for className := range expected {
+		if math.Abs(expected[className]-got[className]) > 1e-12 {
+			t.Errorf("Hashed prediction did not match. Expected: %v, Got: %v", expected, got)
+		}
+	}
+
+	if err := hashed.Untrain(NewObservationFromText([]string{"NotChina"}, "Tokyo Japan Chinese")); err != nil {
+		t.Errorf("Failed to untrain hashed model: %v", err)
+	}
+	if err := hashed.Validate(); err != nil {
+		t.Errorf("Hashed model failed validation: %v", err)
+	}
+
+	reseeded := &HashingOptions{Buckets: 8, Seed: 7}
+	moved := false
+	for _, word := range []string{"Chinese", "Beijing", "Shanghai", "Tokyo", "Japan"} {
+		moved = moved || reseeded.Bucket(word) != hashing.Bucket(word)
+	}
+	if !moved {
+		t.Error("Changing the seed did not change any buckets")
+	}
+
+	hashed.Classes["China"].WordCounts["Chinese"] = 1
+	hashed.Vocabulary["Chinese"] = 1
+	if err := hashed.Validate(); err == nil {
+		t.Error("Word count outside the buckets was accepted")
+	}
+}
+
+func TestHashedModelRequests(t *testing.T) {
+	// setup
+	hashedModel := NewModel("hashed_model")
+	hashedModel.Options.Hashing = &HashingOptions{Buckets: 4, Seed: 1}
+	hashedModelJSON, _ := json.Marshal(hashedModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(hashedModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{"class_a"}, "many different words that share few buckets"))
+	trainRequest, trainRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/hashed_model/train", bytes.NewBuffer(observationJSON))
+	if trainRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", trainRequestErr)
+	}
+	trained := &Model{}
+	_ = unmarshalJSONResponse(t, trainRequest, http.StatusOK, trained)
+	if len(trained.Vocabulary) > 4 || trained.Options.Hashing == nil || trained.Options.Hashing.Seed != 1 {
+		t.Errorf("Hashed model was not trained with buckets. Got: %v", trained)
+	}
+
+	saved := &Model{}
+	loadErr := LoadFromFile(app.modelPath("hashed_model"), saved, json.Unmarshal)
+	if loadErr != nil || saved.Validate() != nil || !reflect.DeepEqual(saved.Vocabulary, trained.Vocabulary) {
+		t.Errorf("Hashed model was not persisted. Got: %v, Error: %v", saved, loadErr)
+	}
+
+	invalidModelJSON := []byte(`{"Name": "invalid_hashed", "Classes": {}, "Vocabulary": {}, "Options": {"Hashing": {"Buckets": 0}}}`)
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(invalidModelJSON))
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &ValidationError{})
+
+	cleanupModel(t, "hashed_model")
+}
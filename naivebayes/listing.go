@@ -0,0 +1,202 @@
+package naivebayes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sort orders accepted by ModelQuery.
+const (
+	SortByName         = "name"
+	SortByUpdated      = "updated"
+	SortByObservations = "observations"
+)
+
+// ModelPage struct.
+// One page of a paginated model listing. NextCursor is empty on the last page.
+type ModelPage struct {
+	Models     []interface{}
+	NextCursor string
+}
+
+/*
+	ModelQuery struct.
+	Selects, orders and pages through models.
+	Labels holds label keys to values a model must have; an empty value only
+	requires the key to be present. A Limit of zero returns every match.
+*/
+type ModelQuery struct {
+	Prefix     string
+	Glob       string
+	Labels     map[string]string
+	Sort       string
+	Descending bool
+	Limit      int
+	Cursor     string
+}
+
+// modelCursor is the decoded form of a pagination cursor: the sort position of
+// the last model on the previous page.
+type modelCursor struct {
+	Name    string
+	Updated time.Time `json:",omitempty"`
+	Count   float64   `json:",omitempty"`
+}
+
+/*
+	parseModelQuery builds a ModelQuery from the request query parameters.
+	* prefix=<p> - names starting with p
+	* name=<glob> - names matching a glob such as "customer-*"
+	* label=<key> or label=<key>:<value> - models with the label, may be repeated
+	* sort=name|updated|observations and order=asc|desc
+	* limit=<n> and cursor=<c> - page size and the NextCursor of the previous page
+*/
+func parseModelQuery(request *JSONRequest) (query *ModelQuery, err error) {
+	query = &ModelQuery{Sort: SortByName, Labels: make(map[string]string)}
+	if prefix := request.Param("prefix"); prefix != nil {
+		query.Prefix = prefix[0]
+	}
+	if glob := request.Param("name"); glob != nil {
+		if _, matchErr := path.Match(glob[0], ""); matchErr != nil {
+			return nil, fmt.Errorf("Invalid name pattern: %s", glob[0])
+		}
+		query.Glob = glob[0]
+	}
+	for _, label := range request.Param("label") {
+		parts := strings.SplitN(label, ":", 2)
+		if len(parts) == 1 {
+			parts = append(parts, "")
+		}
+		query.Labels[parts[0]] = parts[1]
+	}
+	if sortBy := request.Param("sort"); sortBy != nil {
+		query.Sort = sortBy[0]
+		if query.Sort != SortByName && query.Sort != SortByUpdated && query.Sort != SortByObservations {
+			return nil, fmt.Errorf("Invalid sort: %s", query.Sort)
+		}
+	}
+	if order := request.Param("order"); order != nil {
+		if order[0] != "asc" && order[0] != "desc" {
+			return nil, fmt.Errorf("Invalid order: %s", order[0])
+		}
+		query.Descending = order[0] == "desc"
+	}
+	if limit := request.Param("limit"); limit != nil {
+		query.Limit, err = strconv.Atoi(limit[0])
+		if err != nil || query.Limit < 1 {
+			return nil, fmt.Errorf("Invalid limit: %s", limit[0])
+		}
+	}
+	if cursor := request.Param("cursor"); cursor != nil {
+		query.Cursor = cursor[0]
+	}
+	return query, nil
+}
+
+// matchName reports whether a model name passes the name filters.
+// Used to skip loading models that can not match.
+func (q *ModelQuery) matchName(name string) bool {
+	if !strings.HasPrefix(name, q.Prefix) {
+		return false
+	}
+	if q.Glob != "" {
+		matched, _ := path.Match(q.Glob, name)
+		return matched
+	}
+	return true
+}
+
+// Match reports whether the model passes all the query filters.
+func (q *ModelQuery) Match(s *ModelSummary) bool {
+	if !q.matchName(s.Name) {
+		return false
+	}
+	for key, value := range q.Labels {
+		labelValue, ok := s.Labels[key]
+		if !ok || (value != "" && labelValue != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// cursorFor returns the position of the given model in the query's sort order.
+func (q *ModelQuery) cursorFor(s *ModelSummary) modelCursor {
+	switch q.Sort {
+	case SortByUpdated:
+		return modelCursor{Name: s.Name, Updated: s.Updated}
+	case SortByObservations:
+		return modelCursor{Name: s.Name, Count: s.ObservationCount}
+	}
+	return modelCursor{Name: s.Name}
+}
+
+// before reports whether a sorts before b in the query's sort order.
+// Names break ties so the order is always total.
+func (q *ModelQuery) before(a, b modelCursor) bool {
+	less, greater := false, false
+	switch q.Sort {
+	case SortByUpdated:
+		less, greater = a.Updated.Before(b.Updated), a.Updated.After(b.Updated)
+	case SortByObservations:
+		less, greater = a.Count < b.Count, a.Count > b.Count
+	}
+	if !less && !greater {
+		less, greater = a.Name < b.Name, a.Name > b.Name
+	}
+	if q.Descending {
+		return greater
+	}
+	return less
+}
+
+// encodeCursor turns a position into an opaque cursor string.
+func encodeCursor(c modelCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor string created by encodeCursor.
+func decodeCursor(cursor string) (c modelCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("Invalid cursor: %s", cursor)
+	}
+	return c, nil
+}
+
+// Apply filters and sorts the given model summaries and returns the page selected
+// by the query, along with the cursor for the next page.
+func (q *ModelQuery) Apply(summaries []*ModelSummary) (page []*ModelSummary, nextCursor string, err error) {
+	matched := []*ModelSummary{}
+	for _, s := range summaries {
+		if q.Match(s) {
+			matched = append(matched, s)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return q.before(q.cursorFor(matched[i]), q.cursorFor(matched[j])) })
+
+	if q.Cursor != "" {
+		after, cursorErr := decodeCursor(q.Cursor)
+		if cursorErr != nil {
+			return nil, "", cursorErr
+		}
+		start := sort.Search(len(matched), func(i int) bool { return q.before(after, q.cursorFor(matched[i])) })
+		matched = matched[start:]
+	}
+
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+		nextCursor = encodeCursor(q.cursorFor(matched[len(matched)-1]))
+	}
+	return matched, nextCursor, nil
+}
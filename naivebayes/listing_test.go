@@ -0,0 +1,131 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func modelNamesOf(summaries []*ModelSummary) (names []string) {
+	names = []string{}
+	for _, s := range summaries {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// TestModelQuery tests filtering, sorting and paging through models
+func TestModelQuery(t *testing.T) {
+	start := time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC)
+	models := []*Model{}
+	for i, name := range []string{"customer-b", "customer-a", "internal", "customer-c"} {
+		m := NewModel(name)
+		m.ObservationCount = float64(10 - i)
+		m.Metadata.Updated = start.Add(time.Duration(i) * time.Hour)
+		m.Metadata.Labels = map[string]string{"team": "support"}
+		models = append(models, m)
+	}
+	models[2].Metadata.Labels["team"] = "research"
+	summaries := []*ModelSummary{}
+	for _, m := range models {
+		summaries = append(summaries, m.Summary())
+	}
+
+	query := &ModelQuery{Prefix: "customer-", Sort: SortByName}
+	page, next, _ := query.Apply(summaries)
+	if !reflect.DeepEqual(modelNamesOf(page), []string{"customer-a", "customer-b", "customer-c"}) || next != "" {
+		t.Errorf("Prefix filter returned unexpected models: %v", modelNamesOf(page))
+	}
+
+	query = &ModelQuery{Labels: map[string]string{"team": "research"}}
+	page, _, _ = query.Apply(summaries)
+	if !reflect.DeepEqual(modelNamesOf(page), []string{"internal"}) {
+		t.Errorf("Label filter returned unexpected models: %v", modelNamesOf(page))
+	}
+
+	query = &ModelQuery{Glob: "*-[ab]", Sort: SortByObservations}
+	page, _, _ = query.Apply(summaries)
+	if !reflect.DeepEqual(modelNamesOf(page), []string{"customer-a", "customer-b"}) {
+		t.Errorf("Glob filter sorted by observations returned unexpected models: %v", modelNamesOf(page))
+	}
+
+	query = &ModelQuery{Sort: SortByUpdated, Descending: true, Limit: 3}
+	page, next, _ = query.Apply(summaries)
+	if !reflect.DeepEqual(modelNamesOf(page), []string{"customer-c", "internal", "customer-a"}) || next == "" {
+		t.Errorf("First page returned unexpected models: %v", modelNamesOf(page))
+	}
+	query.Cursor = next
+	page, next, _ = query.Apply(summaries)
+	if !reflect.DeepEqual(modelNamesOf(page), []string{"customer-b"}) || next != "" {
+		t.Errorf("Last page returned unexpected models: %v", modelNamesOf(page))
+	}
+
+	query.Cursor = "not a cursor"
+	if _, _, err := query.Apply(summaries); err == nil {
+		t.Error("Invalid cursor did not return an error")
+	}
+}
+
+func TestListModelsPage(t *testing.T) {
+	pageRequest, pageRequestErr := http.NewRequest(http.MethodGet, server.URL+"/models?limit=1&fields=Name", nil)
+	if pageRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", pageRequestErr)
+	}
+	page := &ModelPage{}
+	_ = unmarshalJSONResponse(t, pageRequest, http.StatusOK, page)
+	if len(page.Models) != 1 || page.NextCursor == "" {
+		t.Fatalf("Did not receive expected first page. Got: %v", page)
+	}
+
+	nextRequest, nextRequestErr := http.NewRequest(http.MethodGet, server.URL+"/models?limit=1&fields=Name&cursor="+page.NextCursor, nil)
+	if nextRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", nextRequestErr)
+	}
+	nextPage := &ModelPage{}
+	_ = unmarshalJSONResponse(t, nextRequest, http.StatusOK, nextPage)
+	expected := []interface{}{map[string]interface{}{"Name": "test_model"}}
+	if !reflect.DeepEqual(nextPage.Models, expected) || nextPage.NextCursor != "" {
+		t.Errorf("Did not receive expected last page. Got: %v", nextPage)
+	}
+
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodGet, server.URL+"/models?sort=size", nil)
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &map[string]interface{}{})
+}
+
+// TestListLazyModels tests that a lazily loading app lists models from the summaries
+// recorded when their files were scanned, without reading the files again
+func TestListLazyModels(t *testing.T) {
+	modelDir, dirErr := ioutil.TempDir("", "lazy_models")
+	if dirErr != nil {
+		t.Fatalf("Failed to create model dir: %v", dirErr)
+	}
+	defer os.RemoveAll(modelDir)
+	labelled := NewModel("labelled_model")
+	labelled.Metadata.Labels = map[string]string{"team": "support"}
+	path := modelDir + "/labelled_model.json"
+	if saveErr := SaveToFile(path, labelled, json.Marshal); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+	lazyApp := NewNaiveBayesApp(&Config{ModelDir: modelDir, CacheSize: 1})
+
+	// the file is unreadable now, but its recorded state is unchanged
+	info, _ := os.Stat(path)
+	ioutil.WriteFile(path, invalidJSON, 0644)
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+
+	response := lazyApp.listModels(&JSONRequest{QueryParams: url.Values{"label": {"team:support"}}})
+	if models, ok := response.Data.([]interface{}); !ok || len(models) != 1 || models[0].(*ModelSummary).Name != "labelled_model" {
+		t.Errorf("Lazy app did not list the model from its summary. Got: %v", response.Data)
+	}
+	if stats := lazyApp.models.stats(); stats.Loaded != 0 {
+		t.Errorf("Listing loaded models into the cache. Got: %v", stats)
+	}
+}
@@ -0,0 +1,210 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// compatible checks that the counts of other were made from the same features as the Model's,
+// so the two can be combined.
+func (m *Model) compatible(other *Model) (err error) {
+	if !m.Options.Tokenizer.Equal(other.Options.Tokenizer) {
+		return fmt.Errorf("Models %s and %s have different tokenizers", m.Name, other.Name)
+	}
+	hashing, otherHashing := HashingOptions{}, HashingOptions{}
+	if m.Options.Hashing != nil {
+		hashing = *m.Options.Hashing
+	}
+	if other.Options.Hashing != nil {
+		otherHashing = *other.Options.Hashing
+	}
+	if hashing != otherHashing {
+		return fmt.Errorf("Models %s and %s have different hashing options", m.Name, other.Name)
+	}
+	transform, otherTransform := TransformOptions{}, TransformOptions{}
+	if m.Options.Transform != nil {
+		transform = *m.Options.Transform
+	}
+	if other.Options.Transform != nil {
+		otherTransform = *other.Options.Transform
+	}
+	if transform != otherTransform {
+		return fmt.Errorf("Models %s and %s have different transformations", m.Name, other.Name)
+	}
+	return nil
+}
+
+/*
+	Merge adds the counts of other to the Model, as if the Model had also been trained
+	with other's observations. Classes and words are combined by name.
+	Document counts are only kept for classes where both models have them.
+	Returns an error and leaves the Model unchanged if the models tokenize, hash or
+	transform words differently.
+*/
+func (m *Model) Merge(other *Model) (err error) {
+	if err = m.compatible(other); err != nil {
+		return err
+	}
+	for name, otherClass := range other.Classes {
+		class, ok := m.Classes[name]
+		if !ok {
+			class = NewClass(name)
+			m.Classes[name] = class
+		}
+		class.ObservationCount += otherClass.ObservationCount
+		for word, count := range otherClass.WordCounts {
+			class.addWord(word, count)
+		}
+		if class.DocumentCounts != nil && otherClass.DocumentCounts == nil {
+			class.DocumentCounts = nil
+		}
+		for word, count := range otherClass.DocumentCounts {
+			if class.DocumentCounts != nil {
+				class.DocumentCounts[word] += count
+			}
+		}
+	}
+	for word := range other.Vocabulary {
+		m.Vocabulary[word] = 1
+	}
+	m.ObservationCount += other.ObservationCount
+	m.Metadata.Updated = now()
+	return nil
+}
+
+/*
+	Subtract removes the counts of other from the Model, undoing a Merge. Classes left
+	without observations are removed, as are words that no class counts any more.
+	Returns an error and leaves the Model unchanged if the models are not compatible or
+	other has counts the Model does not.
+*/
+func (m *Model) Subtract(other *Model) (err error) {
+	if err = m.compatible(other); err != nil {
+		return err
+	}
+	if _, ok := subtractCount(m.ObservationCount, other.ObservationCount); !ok {
+		return fmt.Errorf("Model %s has fewer observations than %s", m.Name, other.Name)
+	}
+	for name, otherClass := range other.Classes {
+		class, ok := m.Classes[name]
+		if !ok {
+			return fmt.Errorf("Class not found: %s", name)
+		}
+		if _, ok := subtractCount(class.ObservationCount, otherClass.ObservationCount); !ok {
+			return fmt.Errorf("Class %s has fewer observations in %s than in %s", name, m.Name, other.Name)
+		}
+		for word, count := range otherClass.WordCounts {
+			if _, ok := subtractCount(class.WordCounts[word], count); !ok {
+				return fmt.Errorf("Class %s has counted the word %q fewer times in %s than in %s", name, word, m.Name, other.Name)
+			}
+		}
+	}
+
+	for name, otherClass := range other.Classes {
+		class := m.Classes[name]
+		class.ObservationCount, _ = subtractCount(class.ObservationCount, otherClass.ObservationCount)
+		if class.ObservationCount == 0 {
+			delete(m.Classes, name)
+			continue
+		}
+		for word, count := range otherClass.WordCounts {
+			class.WordCounts[word], _ = subtractCount(class.WordCounts[word], count)
+			if class.WordCounts[word] == 0 {
+				delete(class.WordCounts, word)
+			}
+			class.TotalCount, _ = subtractCount(class.TotalCount, count)
+		}
+		for word, count := range otherClass.DocumentCounts {
+			if documents, ok := class.DocumentCounts[word]; ok {
+				class.DocumentCounts[word], _ = subtractCount(documents, count)
+				if class.DocumentCounts[word] <= 0 || class.WordCounts[word] == 0 {
+					delete(class.DocumentCounts, word)
+				}
+			}
+		}
+	}
+	m.pruneWords(func(class *Class, word string) bool { return true })
+	m.ObservationCount, _ = subtractCount(m.ObservationCount, other.ObservationCount)
+	m.Metadata.Updated = now()
+	return nil
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+// MergeRequest struct.
+// The payload of a request to combine models into a new model named Name.
+// The Models are merged in order and then each of the Subtract models is subtracted.
+type MergeRequest struct {
+	Name     string
+	Models   []string
+	Subtract []string
+}
+
+/*
+   mergeModels creates and saves a new model from the json MergeRequest payload.
+   The new model takes its options from the first model; calibrations are not kept.
+   * POST /models/merge - Merge models into a new model
+*/
+func (app *NaiveBayesApp) mergeModels(request *JSONRequest) *JSONResponse {
+	mergeRequest := &MergeRequest{}
+	unmarshalErr := json.Unmarshal(request.Data, mergeRequest)
+	if unmarshalErr != nil {
+		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+	}
+	if nameErr := ValidateModelName(mergeRequest.Name); nameErr != nil {
+		return &JSONResponse{Error: nameErr, Code: http.StatusBadRequest}
+	}
+	if len(mergeRequest.Models) == 0 {
+		return &JSONResponse{Error: fmt.Errorf("At least one model to merge is required"), Code: http.StatusBadRequest}
+	}
+
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	if _, exists := app.getModel(mergeRequest.Name); exists {
+		return &JSONResponse{Error: fmt.Errorf("Could not create new model. Model %s already exists.", mergeRequest.Name), Code: http.StatusConflict}
+	}
+
+	models := make(map[string]*Model)
+	for _, name := range append(append([]string{}, mergeRequest.Models...), mergeRequest.Subtract...) {
+		model, ok := app.getModel(name)
+		if !ok {
+			return &JSONResponse{Error: fmt.Errorf("Model not found: %s", name), Code: http.StatusNotFound}
+		}
+		models[name] = model
+	}
+
+	merged := models[mergeRequest.Models[0]].Copy()
+	for _, name := range mergeRequest.Models[1:] {
+		if mergeErr := merged.Merge(models[name]); mergeErr != nil {
+			return &JSONResponse{Error: mergeErr, Code: http.StatusBadRequest}
+		}
+	}
+	for _, name := range mergeRequest.Subtract {
+		if subtractErr := merged.Subtract(models[name]); subtractErr != nil {
+			return &JSONResponse{Error: subtractErr, Code: http.StatusBadRequest}
+		}
+	}
+
+	source := "merged from " + strings.Join(mergeRequest.Models, ", ")
+	if len(mergeRequest.Subtract) > 0 {
+		source += " without " + strings.Join(mergeRequest.Subtract, ", ")
+	}
+	merged.Name = mergeRequest.Name
+	merged.Calibration = nil
+	merged.Metadata = Metadata{Source: source, Created: now()}
+	merged.Metadata.Updated = merged.Metadata.Created
+
+	saveErr := app.saveModel(merged)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.add(merged)
+
+	log.Printf("Created model: '%s' %s", merged.Name, source)
+	return &JSONResponse{Data: merged.Summary(), Code: http.StatusOK}
+}
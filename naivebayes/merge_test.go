@@ -0,0 +1,104 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// TestMergeAndSubtract tests that merging matches training one model on everything
+// and that subtracting undoes the merge
+func TestMergeAndSubtract(t *testing.T) {
+	europe := NewModel("europe")
+	europe.Train(NewObservationFromText([]string{"spam"}, "win euros now"))
+	europe.Train(NewObservationFromText([]string{"ham"}, "lunch now"))
+	america := NewModel("america")
+	america.Train(NewObservationFromText([]string{"spam"}, "win dollars"))
+	america.Train(NewObservationFromText([]string{"other"}, "hello"))
+
+	combined := NewModel("combined")
+	for _, o := range []*Observation{
+		NewObservationFromText([]string{"spam"}, "win euros now"),
+		NewObservationFromText([]string{"ham"}, "lunch now"),
+		NewObservationFromText([]string{"spam"}, "win dollars"),
+		NewObservationFromText([]string{"other"}, "hello"),
+	} {
+		combined.Train(o)
+	}
+
+	merged := europe.Copy()
+	if err := merged.Merge(america); err != nil {
+		t.Fatalf("Failed to merge models: %v", err)
+	}
+	if !reflect.DeepEqual(merged.Classes, combined.Classes) || !reflect.DeepEqual(merged.Vocabulary, combined.Vocabulary) || merged.ObservationCount != 4 {
+		t.Errorf("Merged model did not match the combined model. Expected: %v, Got: %v", combined, merged)
+	}
+
+	if err := merged.Subtract(america); err != nil {
+		t.Fatalf("Failed to subtract model: %v", err)
+	}
+	if !reflect.DeepEqual(merged.Classes, europe.Classes) || !reflect.DeepEqual(merged.Vocabulary, europe.Vocabulary) || merged.ObservationCount != 2 {
+		t.Errorf("Subtracting did not undo the merge. Expected: %v, Got: %v", europe, merged)
+	}
+	if err := merged.Subtract(america); err == nil {
+		t.Error("Subtracting counts the model does not have did not return an error")
+	}
+
+	lowercase := NewModel("lowercase")
+	lowercase.Options.Tokenizer.Lowercase = true
+	if err := merged.Merge(lowercase); err == nil {
+		t.Error("Models with different tokenizers were merged")
+	}
+	hashed := NewModel("hashed")
+	hashed.Options.Hashing = &HashingOptions{Buckets: 16}
+	if err := merged.Merge(hashed); err == nil {
+		t.Error("Hashed and unhashed models were merged")
+	}
+}
+
+// TestTokenizerEqual tests that stop word order does not matter
+func TestTokenizerEqual(t *testing.T) {
+	a := Tokenizer{Lowercase: true, StopWords: []string{"the", "a"}}
+	if !a.Equal(Tokenizer{Lowercase: true, StopWords: []string{"a", "the", "a"}}) {
+		t.Error("Tokenizers with reordered stop words were not equal")
+	}
+	if a.Equal(Tokenizer{Lowercase: true, StopWords: []string{"the"}}) || a.Equal(Tokenizer{StopWords: []string{"the", "a"}}) {
+		t.Error("Different tokenizers were equal")
+	}
+}
+
+func TestMergeModels(t *testing.T) {
+	mergeJSON, _ := json.Marshal(&MergeRequest{Name: "merged_model", Models: []string{"test_model", "test_model", "test_model"}, Subtract: []string{"test_model"}})
+	mergeRequest, mergeRequestErr := http.NewRequest(http.MethodPost, server.URL+"/models/merge", bytes.NewBuffer(mergeJSON))
+	if mergeRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", mergeRequestErr)
+	}
+	summary := &ModelSummary{}
+	_ = unmarshalJSONResponse(t, mergeRequest, http.StatusOK, summary)
+	if summary.Name != "merged_model" || summary.ObservationCount != 4 || summary.Source == "" {
+		t.Errorf("Did not receive merged model. Got: %v", summary)
+	}
+
+	saved := &Model{}
+	loadErr := LoadFromFile(app.modelPath("merged_model"), saved, json.Unmarshal)
+	if loadErr != nil || saved.Classes["class_a"].WordCounts["a"] != 8 {
+		t.Errorf("Merged model was not saved. Got: %v, Error: %v", saved, loadErr)
+	}
+
+	conflictRequest, conflictRequestErr := http.NewRequest(http.MethodPost, server.URL+"/models/merge", bytes.NewBuffer(mergeJSON))
+	if conflictRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", conflictRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, conflictRequest, http.StatusConflict, &ModelSummary{})
+
+	missingJSON, _ := json.Marshal(&MergeRequest{Name: "missing_merge", Models: []string{"test_model", "missing_model"}})
+	missingRequest, missingRequestErr := http.NewRequest(http.MethodPost, server.URL+"/models/merge", bytes.NewBuffer(missingJSON))
+	if missingRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", missingRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, missingRequest, http.StatusNotFound, &ModelSummary{})
+
+	cleanupModel(t, "merged_model")
+}
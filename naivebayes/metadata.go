@@ -0,0 +1,97 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+/*
+	MetadataPatch struct.
+	A partial update to a Model's Metadata. Only fields that are set are changed.
+	Labels are merged into the existing labels; a null label value removes that label.
+*/
+type MetadataPatch struct {
+	Description *string
+	Owner       *string
+	Source      *string
+	Labels      map[string]*string
+}
+
+// Validate checks that the patch can be applied.
+func (p *MetadataPatch) Validate() (err error) {
+	v := &ValidationError{}
+	for key := range p.Labels {
+		if key == "" {
+			v.add("Labels", "label keys must not be empty")
+		}
+	}
+	return v.result()
+}
+
+// PatchMetadata applies the given patch to the Model's Metadata and marks the model as updated.
+func (m *Model) PatchMetadata(p *MetadataPatch) {
+	if p.Description != nil {
+		m.Metadata.Description = *p.Description
+	}
+	if p.Owner != nil {
+		m.Metadata.Owner = *p.Owner
+	}
+	if p.Source != nil {
+		m.Metadata.Source = *p.Source
+	}
+	for key, value := range p.Labels {
+		if value == nil {
+			delete(m.Metadata.Labels, key)
+			continue
+		}
+		if m.Metadata.Labels == nil {
+			m.Metadata.Labels = make(map[string]string)
+		}
+		m.Metadata.Labels[key] = *value
+	}
+	if len(m.Metadata.Labels) == 0 {
+		m.Metadata.Labels = nil
+	}
+	m.Metadata.Updated = now()
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   patchModel updates the metadata of a model from the json MetadataPatch payload.
+   * PATCH /model/<name> - Update the model's description, owner, source or labels
+*/
+func (app *NaiveBayesApp) patchModel(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	patch := &MetadataPatch{}
+	unmarshalErr := json.Unmarshal(request.Data, patch)
+	if unmarshalErr != nil {
+		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+	}
+	if validateErr := patch.Validate(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
+
+	app.models.setDirty(model.Name, true)
+	model.PatchMetadata(patch)
+	saveErr := app.saveModel(model)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.setDirty(model.Name, false)
+
+	log.Printf("Updated metadata of model: '%s'", model.Name)
+	return &JSONResponse{Data: model.Summary(), Code: http.StatusOK}
+}
@@ -0,0 +1,57 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPatchModel(t *testing.T) {
+	// setup
+	metadataModel := NewModel("metadata_model")
+	metadataModel.Metadata.Labels = map[string]string{"stage": "beta", "team": "support"}
+	metadataModelJSON, _ := json.Marshal(metadataModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(metadataModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	patch := []byte(`{"Description": "Routes support tickets", "Owner": "support-eng", "Labels": {"stage": null, "region": "eu"}}`)
+	patchRequest, patchRequestErr := http.NewRequest(http.MethodPatch, server.URL+"/model/metadata_model", bytes.NewBuffer(patch))
+	if patchRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", patchRequestErr)
+	}
+	summary := &ModelSummary{}
+	_ = unmarshalJSONResponse(t, patchRequest, http.StatusOK, summary)
+
+	expectedLabels := map[string]string{"team": "support", "region": "eu"}
+	if summary.Description != "Routes support tickets" || summary.Owner != "support-eng" || !reflect.DeepEqual(summary.Labels, expectedLabels) {
+		t.Errorf("Metadata was not patched. Got: %v", summary)
+	}
+	if !summary.Updated.After(metadataModel.Metadata.Updated) {
+		t.Error("Patching metadata did not mark the model as updated")
+	}
+
+	saved := &Model{}
+	loadErr := LoadFromFile(app.modelPath("metadata_model"), saved, json.Unmarshal)
+	if loadErr != nil || saved.Metadata.Owner != "support-eng" {
+		t.Errorf("Patched metadata was not persisted. Got: %v, Error: %v", saved.Metadata, loadErr)
+	}
+
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodPatch, server.URL+"/model/metadata_model", bytes.NewBufferString(`{"Labels": {"": "x"}}`))
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &ValidationError{})
+
+	missingRequest, missingRequestErr := http.NewRequest(http.MethodPatch, server.URL+"/model/missing_model", bytes.NewBuffer(patch))
+	if missingRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", missingRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, missingRequest, http.StatusNotFound, &ModelSummary{})
+
+	cleanupModel(t, "metadata_model")
+}
@@ -0,0 +1,109 @@
+package naivebayes
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultThreshold is the probability a class needs to be predicted as a label
+// when no threshold is configured.
+const DefaultThreshold = 0.5
+
+// MultiLabelOptions struct.
+// Enables multi-label predictions, where every class is decided independently.
+// Thresholds sets the probability each class needs to be predicted; classes without
+// one use Threshold, or DefaultThreshold if that is zero.
+type MultiLabelOptions struct {
+	Threshold  float64            `json:",omitempty"`
+	Thresholds map[string]float64 `json:",omitempty"`
+}
+
+// Copy returns a copy of the MultiLabelOptions that shares no maps with the original.
+func (o *MultiLabelOptions) Copy() *MultiLabelOptions {
+	c := &MultiLabelOptions{Threshold: o.Threshold}
+	if o.Thresholds != nil {
+		c.Thresholds = make(map[string]float64)
+		for className, threshold := range o.Thresholds {
+			c.Thresholds[className] = threshold
+		}
+	}
+	return c
+}
+
+// threshold returns the probability the given class needs to be predicted.
+func (o *MultiLabelOptions) threshold(className string) float64 {
+	if threshold, ok := o.Thresholds[className]; ok {
+		return threshold
+	}
+	if o.Threshold > 0 {
+		return o.Threshold
+	}
+	return DefaultThreshold
+}
+
+// MultiLabelResult struct.
+// The labels predicted for an observation and the probability of every class.
+type MultiLabelResult struct {
+	Labels  []string
+	Classes Prediction
+}
+
+/*
+	PredictOneVsRest calculates the probability of each class for the given observation
+	as a separate binary decision between the class and all the other classes.
+	Unlike Predict the probabilities do not compete, so several classes can be likely.
+	The other classes are pooled into one, so an observation trained with several
+	labels counts towards the complement of each of them through the others.
+*/
+func (m *Model) PredictOneVsRest(o *Observation) (p Prediction) {
+	p = make(map[string]float64)
+	counts := m.features(o)
+
+	// the totals over all classes give each complement without copying word counts
+	totalCount := 0.0
+	for _, class := range m.Classes {
+		totalCount += class.TotalCount
+	}
+	wordTotals := make(map[string]float64)
+	for word := range counts {
+		for _, class := range m.Classes {
+			wordTotals[word] += class.WordCounts[word]
+		}
+	}
+
+	alpha := m.smoothing()
+	vocabulary := float64(len(m.Vocabulary))
+	for _, class := range m.Classes {
+		prior := math.Exp(m.classPriorProbability(class))
+		if prior >= 1 {
+			p[class.Name] = 1
+			continue
+		}
+		complementTotal := totalCount - class.TotalCount
+		score := math.Log(prior / (1 - prior))
+		for word, count := range counts {
+			complementCount := wordTotals[word] - class.WordCounts[word]
+			score += count * (m.wordLogProbability(class, word) - math.Log((complementCount+alpha)/(complementTotal+alpha*vocabulary)))
+		}
+		p[class.Name] = 1 / (1 + math.Exp(-score))
+	}
+	return p
+}
+
+// PredictLabels predicts the set of labels for the given observation, in alphabetical order.
+// Each class is a label if its one-vs-rest probability reaches its threshold.
+// Uses the Model's MultiLabel options, or the default threshold if they are not set.
+func (m *Model) PredictLabels(o *Observation) *MultiLabelResult {
+	options := m.Options.MultiLabel
+	if options == nil {
+		options = &MultiLabelOptions{}
+	}
+	result := &MultiLabelResult{Labels: []string{}, Classes: m.PredictOneVsRest(o)}
+	for className, probability := range result.Classes {
+		if probability >= options.threshold(className) {
+			result.Labels = append(result.Labels, className)
+		}
+	}
+	sort.Strings(result.Labels)
+	return result
+}
@@ -0,0 +1,58 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// TestPredictLabels tests one-vs-rest predictions with per-class thresholds
+func TestPredictLabels(t *testing.T) {
+	model := NewModel("multilabel")
+	model.Train(NewObservationFromText([]string{"sports", "news"}, "match score result"))
+	model.Train(NewObservationFromText([]string{"sports"}, "match goal team"))
+	model.Train(NewObservationFromText([]string{"news"}, "election result vote"))
+	model.Train(NewObservationFromText([]string{"weather"}, "rain sun wind"))
+
+	observation := NewObservationFromText([]string{}, "match result")
+	result := model.PredictLabels(observation)
+	if !reflect.DeepEqual(result.Labels, []string{"news", "sports"}) {
+		t.Errorf("Did not predict expected labels. Got: %v", result)
+	}
+	if result.Classes["weather"] >= DefaultThreshold {
+		t.Errorf("Unrelated class was likely: %v", result.Classes)
+	}
+
+	model.Options.MultiLabel = &MultiLabelOptions{Thresholds: map[string]float64{"news": 0.999}}
+	result = model.PredictLabels(observation)
+	if !reflect.DeepEqual(result.Labels, []string{"sports"}) {
+		t.Errorf("Per-class threshold was not applied. Got: %v", result)
+	}
+}
+
+func TestPredictMultiLabel(t *testing.T) {
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{}, "a a test"))
+	predictRequest, predictRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/predict?multi_label=1", bytes.NewBuffer(observationJSON))
+	if predictRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", predictRequestErr)
+	}
+	result := &MultiLabelResult{}
+	_ = unmarshalJSONResponse(t, predictRequest, http.StatusOK, result)
+	if !reflect.DeepEqual(result.Labels, []string{"class_a"}) || len(result.Classes) != 2 {
+		t.Errorf("Did not receive expected labels. Got: %v", result)
+	}
+
+	thresholdRequest, thresholdRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/predict?multi_label=1&threshold=0.01", bytes.NewBuffer(observationJSON))
+	if thresholdRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", thresholdRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, thresholdRequest, http.StatusOK, result)
+	if !reflect.DeepEqual(result.Labels, []string{"class_a", "class_b"}) {
+		t.Errorf("Threshold parameter was not applied. Got: %v", result)
+	}
+	if model, _ := app.getModel("test_model"); model.Options.MultiLabel != nil {
+		t.Error("Threshold parameter changed the stored model")
+	}
+}
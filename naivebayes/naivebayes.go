@@ -11,15 +11,27 @@ TODO:
 */
 
 import (
+	"fmt"
 	"math"
 	"strings"
+	"time"
 )
 
 // Observation struct.
 // Represents an instance of text to be classified (or for training).
+// Weight is how much the observation counts for when training; zero counts as one.
 type Observation struct {
 	Classes    []string
 	WordCounts map[string]int
+	Weight     float64 `json:",omitempty"`
+}
+
+// weight returns how much the Observation counts for when training.
+func (o *Observation) weight() float64 {
+	if o.Weight == 0 {
+		return 1
+	}
+	return o.Weight
 }
 
 // NewObservationFromText creates an observation object.
@@ -42,32 +54,35 @@ type Prediction map[string]float64
 
 // BestFit filters a prediction down to the best fit,
 // returning just the class name and calulated probability.
+// Ties are broken by class name so the result is always the same.
 func (p *Prediction) BestFit() (bestClassName string, bestProbability float64) {
-	for className, probability := range *p {
-		if bestClassName == "" || probability > bestProbability {
-			bestClassName = className
-			bestProbability = probability
-		}
+	sorted := p.Sorted()
+	if len(sorted) == 0 {
+		return "", 0
 	}
-	return bestClassName, bestProbability
+	return sorted[0].Class, sorted[0].Probability
 }
 
 // Class struct (a.k.a category).
 // Represents a grouping of observations that belong together.
+// Counts are weighted, so they are whole numbers only if every observation had a weight of one.
+// DocumentCounts holds the number of observations each word appeared in. It is nil for
+// classes saved before it was tracked.
 type Class struct {
 	Name             string
-	ObservationCount int
-	WordCounts       map[string]int
-	TotalCount       int
+	ObservationCount float64
+	WordCounts       map[string]float64
+	TotalCount       float64
+	DocumentCounts   map[string]float64
 }
 
 // NewClasse creates an empty class struct.
 func NewClass(name string) *Class {
-	return &Class{Name: name, WordCounts: make(map[string]int), TotalCount: 0}
+	return &Class{Name: name, WordCounts: make(map[string]float64), TotalCount: 0, DocumentCounts: make(map[string]float64)}
 }
 
 // addWord increments the count for the given word on the Class.
-func (c *Class) addWord(word string, count int) {
+func (c *Class) addWord(word string, count float64) {
 	c.WordCounts[word] += count
 	c.TotalCount += count
 }
@@ -78,31 +93,224 @@ func (c *Class) addWord(word string, count int) {
 type Model struct {
 	Name             string
 	Classes          map[string]*Class
-	ObservationCount int
+	ObservationCount float64
 	Vocabulary       map[string]int
+	Options          Options
+	Calibration      *Calibration `json:",omitempty"`
+	Metadata         Metadata
+}
+
+// Options struct.
+// Settings that change how a Model tokenizes text and scores words.
+// Smoothing is the pseudo count added to every word count; zero means Laplace smoothing (one).
+// Optional behaviour is enabled by setting the matching pointer field.
+type Options struct {
+	Smoothing  float64 `json:",omitempty"`
+	Tokenizer  Tokenizer
+	MultiLabel *MultiLabelOptions `json:",omitempty"`
+	Priors     *PriorOptions      `json:",omitempty"`
+	Decay      *DecayOptions      `json:",omitempty"`
+	Pruning    *PruneOptions      `json:",omitempty"`
+	Hashing    *HashingOptions    `json:",omitempty"`
+	Transform  *TransformOptions  `json:",omitempty"`
+}
+
+// Copy returns a copy of the Options that shares nothing with the original.
+func (o Options) Copy() Options {
+	o.Tokenizer = o.Tokenizer.Copy()
+	if o.MultiLabel != nil {
+		o.MultiLabel = o.MultiLabel.Copy()
+	}
+	if o.Priors != nil {
+		o.Priors = o.Priors.Copy()
+	}
+	if o.Decay != nil {
+		o.Decay = o.Decay.Copy()
+	}
+	if o.Pruning != nil {
+		o.Pruning = o.Pruning.Copy()
+	}
+	if o.Hashing != nil {
+		o.Hashing = o.Hashing.Copy()
+	}
+	if o.Transform != nil {
+		o.Transform = o.Transform.Copy()
+	}
+	return o
+}
+
+// smoothing returns the additive smoothing used by the Model.
+func (m *Model) smoothing() float64 {
+	if m.Options.Smoothing <= 0 {
+		return 1
+	}
+	return m.Options.Smoothing
+}
+
+// Metadata struct.
+// Descriptive information about a Model that does not affect predictions.
+// Source records where the model's training data came from.
+type Metadata struct {
+	Description string            `json:",omitempty"`
+	Owner       string            `json:",omitempty"`
+	Source      string            `json:",omitempty"`
+	Labels      map[string]string `json:",omitempty"`
+	Created     time.Time
+	Updated     time.Time
+}
+
+// now returns the current time in a form that survives a round trip through json.
+func now() time.Time {
+	return time.Now().UTC()
 }
 
 // NewModel creates and empty Model with the given name.
 func NewModel(name string) *Model {
-	return &Model{Name: name, Classes: make(map[string]*Class), ObservationCount: 0, Vocabulary: make(map[string]int)}
+	created := now()
+	return &Model{Name: name, Classes: make(map[string]*Class), ObservationCount: 0, Vocabulary: make(map[string]int), Metadata: Metadata{Created: created, Updated: created}}
+}
+
+// Copy returns a deep copy of the Model, sharing no maps with the original.
+func (m *Model) Copy() *Model {
+	c := NewModel(m.Name)
+	c.ObservationCount = m.ObservationCount
+	c.Options = m.Options.Copy()
+	if m.Calibration != nil {
+		c.Calibration = m.Calibration.Copy()
+	}
+	c.Metadata = m.Metadata
+	if m.Metadata.Labels != nil {
+		c.Metadata.Labels = make(map[string]string)
+		for key, value := range m.Metadata.Labels {
+			c.Metadata.Labels[key] = value
+		}
+	}
+	for word, count := range m.Vocabulary {
+		c.Vocabulary[word] = count
+	}
+	for name, class := range m.Classes {
+		classCopy := NewClass(class.Name)
+		classCopy.ObservationCount = class.ObservationCount
+		classCopy.TotalCount = class.TotalCount
+		for word, count := range class.WordCounts {
+			classCopy.WordCounts[word] = count
+		}
+		if class.DocumentCounts == nil {
+			classCopy.DocumentCounts = nil
+		}
+		for word, count := range class.DocumentCounts {
+			classCopy.DocumentCounts[word] = count
+		}
+		c.Classes[name] = classCopy
+	}
+	return c
 }
 
 // Train updates (trains) the Model with the given Observation.
+// Counts are scaled by the Observation's weight. Models with DecayOptions
+// decay their existing counts first, and models with PruneOptions prune their
+// vocabulary afterwards when it is due.
 func (m *Model) Train(o *Observation) {
+	trained := now()
+	m.Decay(trained)
+	counts := m.features(o)
+	weight := o.weight()
 	for _, className := range o.Classes {
 		class, ok := m.Classes[className]
 		if !ok {
 			class = NewClass(className)
 			m.Classes[className] = class
 		}
-		class.ObservationCount++
-		for word, count := range o.WordCounts {
-			class.addWord(word, count)
+		class.ObservationCount += weight
+		for word, count := range counts {
+			class.addWord(word, count*weight)
 			m.Vocabulary[word] = 1
+			if class.DocumentCounts != nil && count > 0 {
+				class.DocumentCounts[word] += weight
+			}
 		}
 	}
 
-	m.ObservationCount++
+	m.ObservationCount += weight
+	m.Metadata.Updated = trained
+	m.autoPrune(weight)
+}
+
+// subtractCount subtracts amount from count. Results within rounding error of zero
+// are snapped to zero. ok is false if amount is more than count.
+func subtractCount(count float64, amount float64) (remaining float64, ok bool) {
+	remaining = count - amount
+	if math.Abs(remaining) <= countTolerance*math.Max(1, count) {
+		return 0, true
+	}
+	return remaining, remaining > 0
+}
+
+/*
+	Untrain removes the given Observation from the Model, undoing a call to Train with
+	the same observation and weight. Classes left without observations are removed, as are
+	words that no class counts any more.
+	Returns an error and leaves the Model unchanged if the Model has not been trained
+	with enough of the observation's classes or words. Models that weight words by IDF
+	can only untrain observations trained since their vocabulary last changed, since
+	the weights depend on it.
+*/
+func (m *Model) Untrain(o *Observation) (err error) {
+	counts := m.features(o)
+	weight := o.weight()
+	if _, ok := subtractCount(m.ObservationCount, weight); !ok {
+		return fmt.Errorf("Model has fewer observations than the observation's weight")
+	}
+	for _, className := range o.Classes {
+		class, ok := m.Classes[className]
+		if !ok {
+			return fmt.Errorf("Class not found: %s", className)
+		}
+		if _, ok := subtractCount(class.ObservationCount, weight); !ok {
+			return fmt.Errorf("Class %s has fewer observations than the observation's weight", className)
+		}
+		for word, count := range counts {
+			if _, ok := subtractCount(class.WordCounts[word], count*weight); !ok {
+				return fmt.Errorf("Class %s has counted the word %q fewer times than the observation", className, word)
+			}
+		}
+	}
+
+	for _, className := range o.Classes {
+		class := m.Classes[className]
+		class.ObservationCount, _ = subtractCount(class.ObservationCount, weight)
+		if class.ObservationCount == 0 {
+			delete(m.Classes, className)
+			continue
+		}
+		for word, count := range counts {
+			class.WordCounts[word], _ = subtractCount(class.WordCounts[word], count*weight)
+			if class.WordCounts[word] == 0 {
+				delete(class.WordCounts, word)
+			}
+			class.TotalCount, _ = subtractCount(class.TotalCount, count*weight)
+			if documents, ok := class.DocumentCounts[word]; ok && count > 0 {
+				class.DocumentCounts[word], _ = subtractCount(documents, weight)
+				if class.DocumentCounts[word] <= 0 || class.WordCounts[word] == 0 {
+					delete(class.DocumentCounts, word)
+				}
+			}
+		}
+	}
+	for word := range counts {
+		counted := false
+		for _, class := range m.Classes {
+			_, ok := class.WordCounts[word]
+			counted = counted || ok
+		}
+		if !counted {
+			delete(m.Vocabulary, word)
+		}
+	}
+
+	m.ObservationCount, _ = subtractCount(m.ObservationCount, weight)
+	m.Metadata.Updated = now()
+	return nil
 }
 
 // Predict caalculates the posterior probabality for the given observation
@@ -110,20 +318,53 @@ func (m *Model) Train(o *Observation) {
 func (m *Model) Predict(o *Observation) (p Prediction) {
 	p = make(map[string]float64)
 
-	for _, class := range m.Classes {
-		classConditionalProbability := m.classPriorProbability(class) + m.classConditionalProbability(class, o)
-		p[class.Name] = math.Exp(classConditionalProbability)
+	for className, score := range m.logScores(m.features(o)) {
+		p[className] = math.Exp(score)
+	}
+	return p
+}
+
+// Posterior calculates the posterior probability of each class for the given
+// observation, normalized so the probabilities sum to one.
+// Unlike Predict the result does not underflow to zero for long observations.
+func (m *Model) Posterior(o *Observation) (p Prediction) {
+	p = make(map[string]float64)
+	scores := m.logScores(m.features(o))
+
+	// log-sum-exp, shifted by the largest score to keep exp in range
+	max := math.Inf(-1)
+	for _, score := range scores {
+		max = math.Max(max, score)
+	}
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - max)
+	}
+	for className, score := range scores {
+		p[className] = math.Exp(score-max) / sum
 	}
 	return p
 }
 
+// logScores calculates the log of the unnormalized posterior of each class
+// for the word counts of an observation, as returned by features.
+func (m *Model) logScores(counts map[string]float64) (scores map[string]float64) {
+	scores = make(map[string]float64)
+	for _, class := range m.Classes {
+		scores[class.Name] = m.classPriorProbability(class) + m.classConditionalProbability(class, counts)
+	}
+	return scores
+}
+
 // classPriorProbability calculates the prior probability of the given class
 // for the Model.
 // P( class ) - Number of occurences of the class / Number of trained observations
+// unless Options.Priors says otherwise.
 func (m *Model) classPriorProbability(class *Class) (p float64) {
-	p = math.Log(float64(class.ObservationCount) / float64(m.ObservationCount))
-	return p
-
+	if m.Options.Priors != nil {
+		return m.configuredPriorProbability(class)
+	}
+	return m.trainingPriorProbability(class)
 }
 
 /*
@@ -138,14 +379,21 @@ func (m *Model) classPriorProbability(class *Class) (p float64) {
 	(words in class plus total number of unique words seen by the model)
 
 	Laplace smoothing (always add one so new words don't break everything)
-	and also add unique word count to denominator.
+	and also add unique word count to denominator. Options.Smoothing replaces the one
+	with a different pseudo count.
 */
-func (m *Model) classConditionalProbability(class *Class, o *Observation) (p float64) {
+func (m *Model) classConditionalProbability(class *Class, counts map[string]float64) (p float64) {
 	p = 0
-	for word, count := range o.WordCounts {
-		classWordCount, _ := class.WordCounts[word]
-		raw := float64(classWordCount+1) / float64(class.TotalCount+len(m.Vocabulary))
-		p = p + (math.Log(raw) * float64(count))
+	for word, count := range counts {
+		p = p + (m.wordLogProbability(class, word) * count)
 	}
 	return p
 }
+
+// wordLogProbability calculates log P( word | class ) with additive smoothing.
+func (m *Model) wordLogProbability(class *Class, word string) (p float64) {
+	classWordCount, _ := class.WordCounts[word]
+	alpha := m.smoothing()
+	raw := (classWordCount + alpha) / (class.TotalCount + alpha*float64(len(m.Vocabulary)))
+	return math.Log(raw)
+}
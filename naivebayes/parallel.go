@@ -0,0 +1,114 @@
+package naivebayes
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// TrainingProgress struct.
+// How far a ParallelTrainer has got.
+type TrainingProgress struct {
+	Trained int
+	Elapsed time.Duration
+}
+
+/*
+	ParallelTrainer struct.
+	Trains a Model from a stream of observations on several goroutines.
+	Each of the Workers trains its own partial model from the observations it takes off
+	the stream, and the partial models are merged into the Model at the end.
+	Workers defaults to the number of CPUs.
+	Progress, if set, is called after every ProgressEvery observations (default 1000)
+	and once more when training finishes. Calls never overlap.
+*/
+type ParallelTrainer struct {
+	Workers       int
+	ProgressEvery int
+	Progress      func(progress TrainingProgress)
+}
+
+// defaultProgressEvery is how many observations are trained between progress reports
+// when ProgressEvery is not set.
+const defaultProgressEvery = 1000
+
+/*
+	Train trains the Model with every observation received until the channel is closed,
+	and returns the number of observations trained.
+	The Model is only changed once the channel is closed and every observation has been
+	trained. If the context is cancelled first, Train stops and returns the context's
+	error, leaving the Model unchanged; producers should also stop sending when it is.
+	Observations are trained as if they had all arrived when training finished, so decay
+	is applied once before the partial models are merged, and vocabulary pruning once
+	after. Models that weight words by IDF can not be trained in parallel, since the
+	weights depend on the order observations are trained in.
+*/
+func (t *ParallelTrainer) Train(ctx context.Context, m *Model, observations <-chan *Observation) (trained int, err error) {
+	if m.Options.Transform != nil && m.Options.Transform.InverseDocumentFrequency {
+		return 0, fmt.Errorf("Models with IDF weighting can not be trained in parallel")
+	}
+	workers := t.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	progressEvery := t.ProgressEvery
+	if progressEvery <= 0 {
+		progressEvery = defaultProgressEvery
+	}
+
+	start := time.Now()
+	var progressMu sync.Mutex
+	report := func(count int) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		trained += count
+		if t.Progress != nil && (count == 0 || trained%progressEvery == 0) {
+			t.Progress(TrainingProgress{Trained: trained, Elapsed: time.Since(start)})
+		}
+	}
+
+	partials := make([]*Model, workers)
+	var wg sync.WaitGroup
+	for i := range partials {
+		partial := NewModel(m.Name)
+		partial.Options = m.Options.Copy()
+		partial.Options.Decay = nil
+		partial.Options.Pruning = nil
+		partials[i] = partial
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case o, ok := <-observations:
+					if !ok {
+						return
+					}
+					partial.Train(o)
+					report(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return trained, ctx.Err()
+	}
+	m.Decay(now())
+	var merged float64
+	for _, partial := range partials {
+		if err = m.Merge(partial); err != nil {
+			return trained, err
+		}
+		merged += partial.ObservationCount
+	}
+	m.autoPrune(merged)
+	report(0)
+	return trained, nil
+}
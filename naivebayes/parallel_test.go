@@ -0,0 +1,74 @@
+package naivebayes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// trainingObservations returns n observations spread over three classes.
+func trainingObservations(n int) (observations []*Observation) {
+	for i := 0; i < n; i++ {
+		observations = append(observations, NewObservationFromText([]string{fmt.Sprintf("class_%d", i%3)}, fmt.Sprintf("word_%d word_%d common", i%7, i%11)))
+	}
+	return observations
+}
+
+// TestParallelTrainer tests that parallel training matches training one observation at a time
+func TestParallelTrainer(t *testing.T) {
+	observations := trainingObservations(1000)
+	sequential := NewModel("parallel")
+	for _, o := range observations {
+		sequential.Train(o)
+	}
+
+	stream := make(chan *Observation)
+	go func() {
+		for _, o := range observations {
+			stream <- o
+		}
+		close(stream)
+	}()
+	progress := []int{}
+	trainer := &ParallelTrainer{Workers: 4, ProgressEvery: 300, Progress: func(p TrainingProgress) { progress = append(progress, p.Trained) }}
+	parallel := NewModel("parallel")
+	trained, err := trainer.Train(context.Background(), parallel, stream)
+	if err != nil || trained != 1000 {
+		t.Fatalf("Parallel training failed. Trained: %d, Error: %v", trained, err)
+	}
+	if !reflect.DeepEqual(parallel.Classes, sequential.Classes) || !reflect.DeepEqual(parallel.Vocabulary, sequential.Vocabulary) || parallel.ObservationCount != 1000 {
+		t.Errorf("Parallel model did not match the sequential model. Expected: %v, Got: %v", sequential, parallel)
+	}
+	if !reflect.DeepEqual(progress, []int{300, 600, 900, 1000}) {
+		t.Errorf("Did not receive expected progress. Got: %v", progress)
+	}
+}
+
+// TestParallelTrainerCancel tests that a cancelled training leaves the model unchanged
+func TestParallelTrainerCancel(t *testing.T) {
+	model := NewModel("parallel")
+	model.Train(NewObservationFromText([]string{"class_0"}, "before"))
+	expected := model.Copy()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := make(chan *Observation)
+	go func() {
+		for _, o := range trainingObservations(10) {
+			stream <- o
+		}
+		cancel()
+	}()
+	trainer := &ParallelTrainer{Workers: 2}
+	if _, err := trainer.Train(ctx, model, stream); err != context.Canceled {
+		t.Errorf("Cancelled training did not return the context error. Got: %v", err)
+	}
+	if !reflect.DeepEqual(model, expected) {
+		t.Errorf("Cancelled training changed the model. Got: %v", model)
+	}
+
+	model.Options.Transform = &TransformOptions{InverseDocumentFrequency: true}
+	if _, err := trainer.Train(context.Background(), model, stream); err == nil {
+		t.Error("Model with IDF weighting was trained in parallel")
+	}
+}
@@ -0,0 +1,70 @@
+package naivebayes
+
+import (
+	"sort"
+)
+
+// UnknownClass is the class name given to predictions that are not confident enough.
+const UnknownClass = "unknown"
+
+// ClassProbability struct.
+// A single class and its predicted probability.
+type ClassProbability struct {
+	Class       string
+	Probability float64
+}
+
+// PredictionResult struct.
+// The outcome of a prediction: the chosen class, or UnknownClass, and the top classes.
+type PredictionResult struct {
+	Class       string
+	Probability float64
+	Classes     []ClassProbability
+}
+
+// Sorted returns the classes of the prediction ordered from most to least probable.
+// Classes with the same probability are ordered by name.
+func (p *Prediction) Sorted() (sorted []ClassProbability) {
+	sorted = []ClassProbability{}
+	for className, probability := range *p {
+		sorted = append(sorted, ClassProbability{Class: className, Probability: probability})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Probability != sorted[j].Probability {
+			return sorted[i].Probability > sorted[j].Probability
+		}
+		return sorted[i].Class < sorted[j].Class
+	})
+	return sorted
+}
+
+// TopK returns the k most probable classes, in the same order as Sorted.
+// A k of zero or less returns every class.
+func (p *Prediction) TopK(k int) []ClassProbability {
+	sorted := p.Sorted()
+	if k > 0 && k < len(sorted) {
+		sorted = sorted[:k]
+	}
+	return sorted
+}
+
+/*
+	Decide returns the best fit class if it is confident enough, otherwise UnknownClass.
+	The best class must have at least minConfidence probability and lead the
+	runner up by at least minMargin. Use on a normalized prediction such as the one
+	returned by Model.Posterior so the thresholds are comparable between observations.
+*/
+func (p *Prediction) Decide(minConfidence float64, minMargin float64) (className string, probability float64) {
+	sorted := p.Sorted()
+	if len(sorted) == 0 {
+		return UnknownClass, 0
+	}
+	best := sorted[0]
+	if best.Probability < minConfidence {
+		return UnknownClass, best.Probability
+	}
+	if len(sorted) > 1 && best.Probability-sorted[1].Probability < minMargin {
+		return UnknownClass, best.Probability
+	}
+	return best.Class, best.Probability
+}
@@ -0,0 +1,86 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// TestPredictionRanking tests sorting, top-k and thresholded decisions
+func TestPredictionRanking(t *testing.T) {
+	p := Prediction{"b": 0.3, "a": 0.3, "c": 0.4}
+
+	expected := []ClassProbability{{"c", 0.4}, {"a", 0.3}, {"b", 0.3}}
+	if sorted := p.Sorted(); !reflect.DeepEqual(sorted, expected) {
+		t.Errorf("Sorted (%v) did not match expected order (%v).", sorted, expected)
+	}
+	if top := p.TopK(2); !reflect.DeepEqual(top, expected[:2]) {
+		t.Errorf("TopK (%v) did not match expected classes (%v).", top, expected[:2])
+	}
+
+	tie := Prediction{"b": 0.5, "a": 0.5}
+	for i := 0; i < 10; i++ {
+		if name, _ := tie.BestFit(); name != "a" {
+			t.Fatalf("Tie was not broken by class name. Got: %s", name)
+		}
+	}
+
+	if name, _ := p.Decide(0.35, 0); name != "c" {
+		t.Errorf("Confident prediction was not decided. Got: %s", name)
+	}
+	if name, _ := p.Decide(0.5, 0); name != UnknownClass {
+		t.Errorf("Prediction below minimum confidence was not unknown. Got: %s", name)
+	}
+	if name, _ := p.Decide(0, 0.2); name != UnknownClass {
+		t.Errorf("Prediction below minimum margin was not unknown. Got: %s", name)
+	}
+}
+
+// TestPosterior tests that posterior probabilities are normalized predictions
+func TestPosterior(t *testing.T) {
+	model := NewModel("posterior")
+	model.Train(NewObservationFromText([]string{"China"}, "Chinese Beijing Chinese"))
+	model.Train(NewObservationFromText([]string{"NotChina"}, "Tokyo Japan Chinese"))
+	observation := NewObservationFromText([]string{}, "Chinese Chinese Tokyo")
+
+	raw := model.Predict(observation)
+	posterior := model.Posterior(observation)
+	if math.Abs(posterior["China"]+posterior["NotChina"]-1) > 1e-9 {
+		t.Errorf("Posterior does not sum to one: %v", posterior)
+	}
+	if math.Abs(posterior["China"]/posterior["NotChina"]-raw["China"]/raw["NotChina"]) > 1e-9 {
+		t.Errorf("Posterior (%v) is not proportional to prediction (%v)", posterior, raw)
+	}
+}
+
+func TestPredictTopK(t *testing.T) {
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{}, "a a a test"))
+
+	topRequest, topRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/predict?top_k=1", bytes.NewBuffer(observationJSON))
+	if topRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", topRequestErr)
+	}
+	result := &PredictionResult{}
+	_ = unmarshalJSONResponse(t, topRequest, http.StatusOK, result)
+	if result.Class != "class_a" || len(result.Classes) != 1 || result.Classes[0].Class != "class_a" {
+		t.Errorf("Did not receive expected prediction result. Got: %v", result)
+	}
+
+	unknownRequest, unknownRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/predict?min_confidence=0.9999", bytes.NewBuffer(observationJSON))
+	if unknownRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", unknownRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, unknownRequest, http.StatusOK, result)
+	if result.Class != UnknownClass || len(result.Classes) != 2 {
+		t.Errorf("Did not receive expected unknown prediction. Got: %v", result)
+	}
+
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/predict?top_k=many", bytes.NewBuffer(observationJSON))
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &PredictionResult{})
+}
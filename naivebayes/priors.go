@@ -0,0 +1,242 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+)
+
+// Prior methods accepted by PriorOptions.
+const (
+	PriorsTraining = "training"
+	PriorsUniform  = "uniform"
+	PriorsExplicit = "explicit"
+	PriorsLearned  = "learned"
+)
+
+/*
+	PriorOptions struct.
+	Replaces the class priors derived from training frequencies.
+	* training - the class frequencies of the training observations (the default)
+	* uniform - every class is equally likely
+	* explicit - the priors given in Classes
+	* learned - the priors in Classes, estimated from unlabeled observations with LearnPriors
+	Classes do not have to sum to one; they are normalized over the Model's classes.
+	A class without a prior in Classes is never predicted.
+*/
+type PriorOptions struct {
+	Method  string
+	Classes map[string]float64 `json:",omitempty"`
+}
+
+// Copy returns a copy of the PriorOptions that shares no maps with the original.
+func (o *PriorOptions) Copy() *PriorOptions {
+	c := &PriorOptions{Method: o.Method}
+	if o.Classes != nil {
+		c.Classes = make(map[string]float64)
+		for className, prior := range o.Classes {
+			c.Classes[className] = prior
+		}
+	}
+	return c
+}
+
+// validate records problems with the PriorOptions under the given field.
+func (o *PriorOptions) validate(v *ValidationError, field string) {
+	switch o.Method {
+	case PriorsTraining, PriorsUniform:
+	case PriorsExplicit, PriorsLearned:
+		sum := 0.0
+		for className, prior := range o.Classes {
+			if prior < 0 {
+				v.add(field+".Classes."+className, "must not be negative")
+			}
+			sum += prior
+		}
+		if sum <= 0 {
+			v.add(field+".Classes", "at least one class must have a positive prior")
+		}
+	default:
+		v.add(field+".Method", "must be one of %q, %q, %q or %q", PriorsTraining, PriorsUniform, PriorsExplicit, PriorsLearned)
+	}
+}
+
+// trainingPriorProbability calculates log P( class ) from the training frequencies.
+func (m *Model) trainingPriorProbability(class *Class) (p float64) {
+	return math.Log(class.ObservationCount / m.ObservationCount)
+}
+
+// configuredPriorProbability calculates log P( class ) with the Model's prior options.
+func (m *Model) configuredPriorProbability(class *Class) (p float64) {
+	options := m.Options.Priors
+	switch options.Method {
+	case PriorsUniform:
+		return -math.Log(float64(len(m.Classes)))
+	case PriorsExplicit, PriorsLearned:
+		sum := 0.0
+		for className := range m.Classes {
+			sum += options.Classes[className]
+		}
+		if sum <= 0 {
+			return -math.Log(float64(len(m.Classes)))
+		}
+		return math.Log(options.Classes[class.Name] / sum)
+	}
+	return m.trainingPriorProbability(class)
+}
+
+// Priors returns the prior probability of every class of the Model.
+func (m *Model) Priors() (p Prediction) {
+	p = make(map[string]float64)
+	for _, class := range m.Classes {
+		p[class.Name] = math.Exp(m.classPriorProbability(class))
+	}
+	return p
+}
+
+/*
+	LearnPriors estimates the class priors of the population the given unlabeled
+	observations were drawn from, with the EM procedure of Saerens, Latinne and Decaestecker,
+	"Adjusting the outputs of a classifier to new a priori probabilities".
+	Starting from the training priors, each step predicts every observation with the
+	current priors and takes the mean posterior of each class as the new priors.
+	The Model is not changed; set the result as Options.Priors to use it.
+*/
+func (m *Model) LearnPriors(observations []*Observation) (options *PriorOptions, err error) {
+	if len(observations) == 0 {
+		return nil, fmt.Errorf("Learning priors needs at least one observation")
+	}
+	if len(m.Classes) == 0 || m.ObservationCount == 0 {
+		return nil, fmt.Errorf("Learning priors needs a trained model")
+	}
+
+	// the class conditional probabilities do not change between steps
+	likelihoods := make([]map[string]float64, len(observations))
+	for i, o := range observations {
+		counts := m.features(o)
+		likelihoods[i] = make(map[string]float64)
+		for _, class := range m.Classes {
+			likelihoods[i][class.Name] = m.classConditionalProbability(class, counts)
+		}
+	}
+
+	priors := make(map[string]float64)
+	for _, class := range m.Classes {
+		priors[class.Name] = class.ObservationCount / m.ObservationCount
+	}
+	for iteration := 0; iteration < 1000; iteration++ {
+		next := make(map[string]float64)
+		for _, likelihood := range likelihoods {
+			scores := make(map[string]float64)
+			max := math.Inf(-1)
+			for className, prior := range priors {
+				scores[className] = math.Log(prior) + likelihood[className]
+				max = math.Max(max, scores[className])
+			}
+			sum := 0.0
+			for _, score := range scores {
+				sum += math.Exp(score - max)
+			}
+			for className, score := range scores {
+				next[className] += math.Exp(score-max) / sum / float64(len(observations))
+			}
+		}
+
+		change := 0.0
+		for className, prior := range next {
+			change = math.Max(change, math.Abs(prior-priors[className]))
+		}
+		priors = next
+		if change < 1e-9 {
+			break
+		}
+	}
+	return &PriorOptions{Method: PriorsLearned, Classes: priors}, nil
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+// PriorsRequest struct.
+// The payload of a request to change a Model's priors. Observations are the
+// unlabeled sample used when Method is "learned".
+type PriorsRequest struct {
+	Method       string
+	Classes      map[string]float64
+	Observations []*Observation
+}
+
+/*
+   viewModelPriors returns the prior probability of each class of a model.
+   * GET /model/<name>/priors - View the model's class priors
+*/
+func (app *NaiveBayesApp) viewModelPriors(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+	return &JSONResponse{Data: model.Priors(), Code: http.StatusOK}
+}
+
+/*
+   setModelPriors changes how a model calculates class priors from the json PriorsRequest payload,
+   and returns the resulting prior probability of each class.
+   * POST /model/<name>/priors - Use training, uniform, explicit or learned priors
+*/
+func (app *NaiveBayesApp) setModelPriors(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	priorsRequest := &PriorsRequest{}
+	unmarshalErr := json.Unmarshal(request.Data, priorsRequest)
+	if unmarshalErr != nil {
+		return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+	}
+
+	options := &PriorOptions{Method: priorsRequest.Method, Classes: priorsRequest.Classes}
+	if options.Method == PriorsLearned {
+		for _, observation := range priorsRequest.Observations {
+			if validateErr := observation.Validate(); validateErr != nil {
+				return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+			}
+		}
+		var learnErr error
+		options, learnErr = model.LearnPriors(priorsRequest.Observations)
+		if learnErr != nil {
+			return &JSONResponse{Error: learnErr, Code: http.StatusBadRequest}
+		}
+	}
+	v := &ValidationError{}
+	options.validate(v, "Priors")
+	if validateErr := v.result(); validateErr != nil {
+		return &JSONResponse{Error: validateErr, Code: http.StatusBadRequest}
+	}
+
+	app.models.setDirty(model.Name, true)
+	model.Options.Priors = options
+	if options.Method == PriorsTraining {
+		model.Options.Priors = nil
+	}
+	model.Metadata.Updated = now()
+	saveErr := app.saveModel(model)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.setDirty(model.Name, false)
+
+	log.Printf("Set %s priors of model: '%s'", options.Method, model.Name)
+	return &JSONResponse{Data: model.Priors(), Code: http.StatusOK}
+}
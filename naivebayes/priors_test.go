@@ -0,0 +1,113 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// TestPriors tests uniform, explicit and learned priors against training frequencies
+func TestPriors(t *testing.T) {
+	model := NewModel("priors")
+	model.Train(NewObservationFromText([]string{"ham"}, "lunch meeting"))
+	model.Train(NewObservationFromText([]string{"ham"}, "meeting notes"))
+	model.Train(NewObservationFromText([]string{"ham"}, "lunch today"))
+	model.Train(NewObservationFromText([]string{"spam"}, "win money"))
+
+	if priors := model.Priors(); math.Abs(priors["ham"]-0.75) > 1e-12 {
+		t.Errorf("Did not get training priors. Got: %v", priors)
+	}
+
+	model.Options.Priors = &PriorOptions{Method: PriorsUniform}
+	if priors := model.Priors(); math.Abs(priors["ham"]-0.5) > 1e-12 || math.Abs(priors["spam"]-0.5) > 1e-12 {
+		t.Errorf("Did not get uniform priors. Got: %v", priors)
+	}
+
+	model.Options.Priors = &PriorOptions{Method: PriorsExplicit, Classes: map[string]float64{"ham": 1, "spam": 9}}
+	if priors := model.Priors(); math.Abs(priors["spam"]-0.9) > 1e-12 {
+		t.Errorf("Did not get explicit priors. Got: %v", priors)
+	}
+	posterior := model.Posterior(NewObservationFromText([]string{}, "meeting money"))
+	if class, _ := posterior.BestFit(); class != "spam" {
+		t.Errorf("Explicit priors did not change the prediction. Got: %s", class)
+	}
+
+	// production traffic that is mostly spam
+	sample := []*Observation{NewObservationFromText([]string{}, "lunch meeting")}
+	for i := 0; i < 9; i++ {
+		sample = append(sample, NewObservationFromText([]string{}, "win money"))
+	}
+	model.Options.Priors = nil
+	learned, err := model.LearnPriors(sample)
+	if err != nil {
+		t.Fatalf("Failed to learn priors: %v", err)
+	}
+	if learned.Method != PriorsLearned || learned.Classes["spam"] < 0.8 || math.Abs(learned.Classes["spam"]+learned.Classes["ham"]-1) > 1e-9 {
+		t.Errorf("Did not learn expected priors. Got: %v", learned)
+	}
+
+	model.Options.Priors = &PriorOptions{Method: PriorsExplicit, Classes: map[string]float64{"ham": -1}}
+	if err := model.Validate(); err == nil {
+		t.Error("Negative priors were accepted")
+	}
+}
+
+func TestModelPriors(t *testing.T) {
+	// setup
+	priorsModel := NewModel("priors_model")
+	priorsModel.Train(NewObservationFromText([]string{"class_a"}, "a a test"))
+	priorsModel.Train(NewObservationFromText([]string{"class_a"}, "a test"))
+	priorsModel.Train(NewObservationFromText([]string{"class_b"}, "b text"))
+	priorsModelJSON, _ := json.Marshal(priorsModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(priorsModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	uniformRequest, uniformRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/priors_model/priors", bytes.NewBufferString(`{"Method": "uniform"}`))
+	if uniformRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", uniformRequestErr)
+	}
+	priors := Prediction{}
+	_ = unmarshalJSONResponse(t, uniformRequest, http.StatusOK, &priors)
+	if priors["class_a"] != 0.5 || priors["class_b"] != 0.5 {
+		t.Errorf("Did not receive uniform priors. Got: %v", priors)
+	}
+
+	saved := &Model{}
+	loadErr := LoadFromFile(app.modelPath("priors_model"), saved, json.Unmarshal)
+	if loadErr != nil || saved.Options.Priors == nil || saved.Options.Priors.Method != PriorsUniform {
+		t.Errorf("Priors were not persisted. Error: %v", loadErr)
+	}
+
+	learnedJSON, _ := json.Marshal(&PriorsRequest{Method: PriorsLearned, Observations: []*Observation{NewObservationFromText([]string{}, "b text")}})
+	learnedRequest, learnedRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/priors_model/priors", bytes.NewBuffer(learnedJSON))
+	if learnedRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", learnedRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, learnedRequest, http.StatusOK, &priors)
+	if priors["class_b"] < 0.5 {
+		t.Errorf("Did not receive learned priors. Got: %v", priors)
+	}
+
+	viewRequest, viewRequestErr := http.NewRequest(http.MethodGet, server.URL+"/model/priors_model/priors", nil)
+	if viewRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", viewRequestErr)
+	}
+	viewed := Prediction{}
+	_ = unmarshalJSONResponse(t, viewRequest, http.StatusOK, &viewed)
+	if viewed["class_b"] != priors["class_b"] {
+		t.Errorf("Did not receive stored priors. Expected: %v, Got: %v", priors, viewed)
+	}
+
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/priors_model/priors", bytes.NewBufferString(`{"Method": "explicit"}`))
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &ValidationError{})
+
+	cleanupModel(t, "priors_model")
+}
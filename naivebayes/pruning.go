@@ -0,0 +1,92 @@
+package naivebayes
+
+/*
+	PruneOptions struct.
+	Thresholds for removing words from a Model's vocabulary. Zero disables a threshold.
+	* MinCount - words counted fewer times than this across all classes
+	* MinDocuments - words that appeared in fewer observations than this
+	* MaxDocumentRatio - words that appeared in more than this fraction of observations
+	When set in Options the Model prunes itself during training, each time Every more
+	observations have been trained since it last pruned, counted in Trained. Pruning after every observation
+	(Every of zero) removes new words before they can reach MinCount.
+*/
+type PruneOptions struct {
+	MinCount         float64 `json:",omitempty"`
+	MinDocuments     float64 `json:",omitempty"`
+	MaxDocumentRatio float64 `json:",omitempty"`
+	Every            float64 `json:",omitempty"`
+	Trained          float64 `json:",omitempty"`
+}
+
+// Copy returns a copy of the PruneOptions.
+func (o *PruneOptions) Copy() *PruneOptions {
+	c := *o
+	return &c
+}
+
+// validate records problems with the PruneOptions under the given field.
+func (o *PruneOptions) validate(v *ValidationError, field string) {
+	if o.MinCount < 0 {
+		v.add(field+".MinCount", "must not be negative")
+	}
+	if o.MinDocuments < 0 {
+		v.add(field+".MinDocuments", "must not be negative")
+	}
+	if o.MaxDocumentRatio < 0 || o.MaxDocumentRatio > 1 {
+		v.add(field+".MaxDocumentRatio", "must be between 0 and 1")
+	}
+	if o.Every < 0 {
+		v.add(field+".Every", "must not be negative")
+	}
+}
+
+// PruneVocabulary removes the words that fail the thresholds of the given options from
+// every class and from the Vocabulary, and returns the number of words removed.
+func (m *Model) PruneVocabulary(options *PruneOptions) (removed int) {
+	counts := make(map[string]float64)
+	documents := make(map[string]float64)
+	for _, class := range m.Classes {
+		for word, count := range class.WordCounts {
+			counts[word] += count
+			documents[word] += class.documentCount(word)
+		}
+	}
+
+	prune := make(map[string]bool)
+	for word := range m.Vocabulary {
+		switch {
+		case options.MinCount > 0 && counts[word] < options.MinCount:
+			prune[word] = true
+		case options.MinDocuments > 0 && documents[word] < options.MinDocuments:
+			prune[word] = true
+		case options.MaxDocumentRatio > 0 && documents[word] > options.MaxDocumentRatio*m.ObservationCount:
+			prune[word] = true
+		}
+	}
+	if len(prune) == 0 {
+		return 0
+	}
+
+	m.pruneWords(func(class *Class, word string) bool { return !prune[word] })
+	for word := range prune {
+		delete(m.Vocabulary, word)
+	}
+	m.Metadata.Updated = now()
+	return len(prune)
+}
+
+// autoPrune counts the weight of newly trained observations and prunes the Model's
+// vocabulary if its PruneOptions are due. The ObservationCount is not used because
+// decay, Untrain and Subtract shrink it.
+func (m *Model) autoPrune(trained float64) {
+	options := m.Options.Pruning
+	if options == nil {
+		return
+	}
+	options.Trained += trained
+	if options.Trained < options.Every {
+		return
+	}
+	m.PruneVocabulary(options)
+	options.Trained = 0
+}
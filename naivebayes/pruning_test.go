@@ -0,0 +1,87 @@
+package naivebayes
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestPruneVocabulary tests each threshold and that totals stay consistent
+func TestPruneVocabulary(t *testing.T) {
+	model := NewModel("pruning")
+	model.Train(NewObservationFromText([]string{"spam"}, "win money the"))
+	model.Train(NewObservationFromText([]string{"spam"}, "win win the"))
+	model.Train(NewObservationFromText([]string{"ham"}, "lunch wni the"))
+	model.Train(NewObservationFromText([]string{"ham"}, "lunch the"))
+
+	removed := model.PruneVocabulary(&PruneOptions{MinCount: 2, MaxDocumentRatio: 0.9})
+	if removed != 3 || !reflect.DeepEqual(model.Vocabulary, map[string]int{"win": 1, "lunch": 1}) {
+		t.Errorf("Did not prune expected words. Removed: %d, Got: %v", removed, model.Vocabulary)
+	}
+	if model.Classes["spam"].TotalCount != 3 || model.Classes["ham"].TotalCount != 2 {
+		t.Errorf("Totals were not updated. Got: %v, %v", model.Classes["spam"], model.Classes["ham"])
+	}
+	if err := model.Validate(); err != nil {
+		t.Errorf("Pruned model failed validation: %v", err)
+	}
+
+	if removed := model.PruneVocabulary(&PruneOptions{MinDocuments: 2}); removed != 0 {
+		t.Errorf("Words in two observations were pruned. Removed: %d", removed)
+	}
+	if removed := model.PruneVocabulary(&PruneOptions{MinDocuments: 3}); removed != 2 || len(model.Vocabulary) != 0 {
+		t.Errorf("Did not prune by document frequency. Removed: %d, Got: %v", removed, model.Vocabulary)
+	}
+}
+
+// TestAutoPrune tests that training prunes the vocabulary when it is due
+func TestAutoPrune(t *testing.T) {
+	model := NewModel("pruning")
+	model.Options.Pruning = &PruneOptions{MinCount: 2, Every: 3}
+	model.Train(NewObservationFromText([]string{"spam"}, "win typo"))
+	model.Train(NewObservationFromText([]string{"spam"}, "win"))
+	if _, ok := model.Vocabulary["typo"]; !ok {
+		t.Error("Vocabulary was pruned before it was due")
+	}
+	model.Train(NewObservationFromText([]string{"spam"}, "win tpyo"))
+	if !reflect.DeepEqual(model.Vocabulary, map[string]int{"win": 1}) || model.Options.Pruning.Trained != 0 {
+		t.Errorf("Vocabulary was not pruned when due. Got: %v", model.Vocabulary)
+	}
+}
+
+// TestAutoPruneWithDecay tests that pruning stays due while decay shrinks the observation count
+func TestAutoPruneWithDecay(t *testing.T) {
+	model := NewModel("pruning")
+	model.Options.Decay = &DecayOptions{HalfLife: Duration(time.Hour)}
+	model.Options.Pruning = &PruneOptions{MinCount: 2, Every: 10}
+	for i := 0; i < 10; i++ {
+		model.Options.Decay.Decayed = time.Now().Add(-time.Hour)
+		model.Train(NewObservationFromText([]string{"spam"}, "win"))
+	}
+	if model.ObservationCount >= 10 {
+		t.Fatalf("Observation count did not decay. Got: %v", model.ObservationCount)
+	}
+	if len(model.Vocabulary) != 0 || model.Options.Pruning.Trained != 0 {
+		t.Errorf("Vocabulary was not pruned when due. Got: %v, %v", model.Vocabulary, model.Options.Pruning)
+	}
+}
+
+func TestPruneModelThresholds(t *testing.T) {
+	pruneRequest, pruneRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/prune?max_document_ratio=0.5&target=threshold_model", nil)
+	if pruneRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", pruneRequestErr)
+	}
+	summary := &ModelSummary{}
+	_ = unmarshalJSONResponse(t, pruneRequest, http.StatusOK, summary)
+	if summary.VocabularySize != 2 || summary.Classes[0].TotalCount != 4 {
+		t.Errorf("Did not receive pruned model. Got: %v", summary)
+	}
+
+	invalidRequest, invalidRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/test_model/prune?max_document_ratio=2", nil)
+	if invalidRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", invalidRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, invalidRequest, http.StatusBadRequest, &ValidationError{})
+
+	cleanupModel(t, "threshold_model")
+}
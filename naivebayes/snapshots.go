@@ -0,0 +1,266 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot struct.
+// Represents a saved copy of a model at a point in time. Snapshots are numbered
+// sequentially per model and can optionally be given a name.
+type Snapshot struct {
+	Version int
+	Name    string
+	Created time.Time
+	Model   *Model `json:",omitempty"`
+}
+
+// SnapshotPolicy struct.
+// Controls how many snapshots are retained for each model.
+// A zero value for either field disables that limit.
+type SnapshotPolicy struct {
+	MaxCount int
+	MaxAge   time.Duration
+}
+
+// snapshotDir returns the storage directory for the snapshots of a given modelName
+func (app *NaiveBayesApp) snapshotDir(modelName string) (path string) {
+	return app.modelDir + "/snapshots/" + url.PathEscape(modelName)
+}
+
+// snapshotPath returns the storage path for a given snapshot version of modelName
+func (app *NaiveBayesApp) snapshotPath(modelName string, version int) (path string) {
+	return app.snapshotDir(modelName) + "/" + strconv.Itoa(version) + ".json"
+}
+
+// snapshotHeader is a Snapshot without its model. Decoding into it skips building the
+// model's maps when only the versions and names are needed.
+type snapshotHeader struct {
+	Version int
+	Name    string
+	Created time.Time
+}
+
+// loadSnapshots loads all the snapshots stored for the given model, ordered by version.
+// When withModels is false the stored models are not decoded, to keep listing cheap.
+func (app *NaiveBayesApp) loadSnapshots(modelName string, withModels bool) (snapshots []*Snapshot, err error) {
+	files, err := ioutil.ReadDir(app.snapshotDir(modelName))
+	if os.IsNotExist(err) {
+		return []*Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshots = []*Snapshot{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := app.snapshotDir(modelName) + "/" + file.Name()
+		snapshot := &Snapshot{}
+		if withModels {
+			err = LoadFromFile(path, snapshot, json.Unmarshal)
+		} else {
+			header := &snapshotHeader{}
+			err = LoadFromFile(path, header, json.Unmarshal)
+			snapshot = &Snapshot{Version: header.Version, Name: header.Name, Created: header.Created}
+		}
+		if err != nil {
+			log.Printf("Failed to load snapshot from file: '%s' with error: '%s'", file.Name(), err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Version < snapshots[j].Version })
+	return snapshots, nil
+}
+
+// loadSnapshot loads one snapshot of the given model with its stored model.
+// Returns nil if there is no snapshot with that version.
+func (app *NaiveBayesApp) loadSnapshot(modelName string, version int) (snapshot *Snapshot, err error) {
+	path := app.snapshotPath(modelName, version)
+	if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+		return nil, nil
+	}
+	snapshot = &Snapshot{}
+	err = LoadFromFile(path, snapshot, json.Unmarshal)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// findSnapshot returns the snapshot of the given model referred to by ref,
+// which is either a version number or a snapshot name. Only the snapshot that
+// is found has its model loaded.
+func (app *NaiveBayesApp) findSnapshot(modelName string, ref string) (snapshot *Snapshot, err error) {
+	if version, convErr := strconv.Atoi(ref); convErr == nil {
+		return app.loadSnapshot(modelName, version)
+	}
+	snapshots, err := app.loadSnapshots(modelName, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snapshots {
+		if ref != "" && s.Name == ref {
+			return app.loadSnapshot(modelName, s.Version)
+		}
+	}
+	return nil, nil
+}
+
+// createSnapshot saves a copy of the given model as its next numbered snapshot
+// and then applies the app's retention policy.
+func (app *NaiveBayesApp) createSnapshot(model *Model, name string) (snapshot *Snapshot, err error) {
+	snapshots, err := app.loadSnapshots(model.Name, false)
+	if err != nil {
+		return nil, err
+	}
+	version := 1
+	for _, s := range snapshots {
+		if s.Version >= version {
+			version = s.Version + 1
+		}
+	}
+
+	err = os.MkdirAll(app.snapshotDir(model.Name), 0775)
+	if err != nil {
+		return nil, err
+	}
+	snapshot = &Snapshot{Version: version, Name: name, Created: time.Now().UTC(), Model: model.Copy()}
+	err = SaveToFile(app.snapshotPath(model.Name, version), snapshot, json.Marshal)
+	if err != nil {
+		return nil, err
+	}
+
+	app.pruneSnapshots(model.Name, append(snapshots, snapshot))
+	return snapshot, nil
+}
+
+// pruneSnapshots removes snapshots that fall outside the app's retention policy.
+// The most recent snapshot is always kept.
+func (app *NaiveBayesApp) pruneSnapshots(modelName string, snapshots []*Snapshot) {
+	now := time.Now()
+	for i, s := range snapshots {
+		remaining := len(snapshots) - i
+		if remaining <= 1 {
+			break
+		}
+		tooMany := app.snapshotPolicy.MaxCount > 0 && remaining > app.snapshotPolicy.MaxCount
+		tooOld := app.snapshotPolicy.MaxAge > 0 && now.Sub(s.Created) > app.snapshotPolicy.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		err := os.Remove(app.snapshotPath(modelName, s.Version))
+		if err != nil {
+			log.Printf("Failed to remove snapshot %d of model '%s': %v", s.Version, modelName, err)
+		}
+	}
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   createModelSnapshot saves the current state of a model as a new snapshot.
+   An optional json payload of the form {"Name": "..."} names the snapshot.
+   * POST /model/<name>/snapshots - Snapshot the given model
+*/
+func (app *NaiveBayesApp) createModelSnapshot(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	snapshotRequest := &Snapshot{}
+	if len(request.Data) > 0 {
+		unmarshalErr := json.Unmarshal(request.Data, snapshotRequest)
+		if unmarshalErr != nil {
+			return &JSONResponse{Error: unmarshalErr, Code: http.StatusBadRequest}
+		}
+	}
+	if _, convErr := strconv.Atoi(snapshotRequest.Name); convErr == nil {
+		return &JSONResponse{Error: fmt.Errorf("Snapshot names can not be numeric."), Code: http.StatusBadRequest}
+	}
+
+	existing, findErr := app.findSnapshot(modelName, snapshotRequest.Name)
+	if findErr != nil {
+		return &JSONResponse{Error: findErr, Code: http.StatusInternalServerError}
+	}
+	if existing != nil {
+		return &JSONResponse{Error: fmt.Errorf("Snapshot %s already exists for model %s.", snapshotRequest.Name, modelName), Code: http.StatusConflict}
+	}
+
+	snapshot, snapshotErr := app.createSnapshot(model, snapshotRequest.Name)
+	if snapshotErr != nil {
+		return &JSONResponse{Error: snapshotErr, Code: http.StatusInternalServerError}
+	}
+
+	log.Printf("Created snapshot %d of model: '%s'", snapshot.Version, modelName)
+	snapshot.Model = nil
+	return &JSONResponse{Data: snapshot, Code: http.StatusOK}
+}
+
+/*
+   listModelSnapshots displays the snapshots of a model, oldest first.
+   * GET /model/<name>/snapshots - List the snapshots of the given model
+*/
+func (app *NaiveBayesApp) listModelSnapshots(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	if _, ok := app.getModel(modelName); !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	snapshots, err := app.loadSnapshots(modelName, false)
+	if err != nil {
+		return &JSONResponse{Error: err, Code: http.StatusInternalServerError}
+	}
+	return &JSONResponse{Data: snapshots, Code: http.StatusOK}
+}
+
+/*
+   restoreModelSnapshot replaces a model with one of its snapshots.
+   The snapshot is referred to by version number or name.
+   * POST /model/<name>/snapshots/<snapshot>/restore - Roll the given model back
+*/
+func (app *NaiveBayesApp) restoreModelSnapshot(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	if _, ok := app.getModel(modelName); !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	snapshot, err := app.findSnapshot(modelName, request.PathVar("snapshot"))
+	if err != nil {
+		return &JSONResponse{Error: err, Code: http.StatusInternalServerError}
+	}
+	if snapshot == nil || snapshot.Model == nil {
+		return &JSONResponse{Error: fmt.Errorf("Snapshot not found"), Code: http.StatusNotFound}
+	}
+
+	model := snapshot.Model
+	model.Name = modelName
+	saveErr := app.saveModel(model)
+	if saveErr != nil {
+		return &JSONResponse{Error: saveErr, Code: http.StatusInternalServerError}
+	}
+	app.models.add(model)
+
+	log.Printf("Restored model: '%s' to snapshot %d", modelName, snapshot.Version)
+	return &JSONResponse{Data: model.Copy(), Code: http.StatusOK}
+}
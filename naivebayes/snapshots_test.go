@@ -0,0 +1,143 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func cleanupSnapshots(t *testing.T, modelName string) {
+	cleanUpErr := os.RemoveAll(app.snapshotDir(modelName))
+	if cleanUpErr != nil {
+		t.Fatalf("Failed to clean up snapshots for model: %s test: %v", modelName, cleanUpErr)
+	}
+	os.Remove(app.modelDir + "/snapshots")
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	// setup
+	snapshotModel := NewModel("snapshot_model")
+	snapshotModel.Train(NewObservationFromText([]string{"first"}, "first batch"))
+	snapshotModelJSON, _ := json.Marshal(snapshotModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(snapshotModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	snapshotRequest, snapshotRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/snapshot_model/snapshots", bytes.NewBufferString(`{"Name": "before"}`))
+	if snapshotRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", snapshotRequestErr)
+	}
+	snapshot := &Snapshot{}
+	_ = unmarshalJSONResponse(t, snapshotRequest, http.StatusOK, snapshot)
+	if snapshot.Version != 1 || snapshot.Name != "before" || snapshot.Model != nil {
+		t.Errorf("Did not receive expected snapshot. Got: %v", snapshot)
+	}
+
+	duplicateRequest, duplicateRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/snapshot_model/snapshots", bytes.NewBufferString(`{"Name": "before"}`))
+	if duplicateRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", duplicateRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, duplicateRequest, http.StatusConflict, &Snapshot{})
+
+	badObservationJSON, _ := json.Marshal(NewObservationFromText([]string{"second"}, "bad batch"))
+	trainRequest, trainRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/snapshot_model/train", bytes.NewBuffer(badObservationJSON))
+	if trainRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", trainRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, trainRequest, http.StatusOK, &Model{})
+
+	listRequest, listRequestErr := http.NewRequest(http.MethodGet, server.URL+"/model/snapshot_model/snapshots", nil)
+	if listRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", listRequestErr)
+	}
+	snapshots := []*Snapshot{}
+	_ = unmarshalJSONResponse(t, listRequest, http.StatusOK, &snapshots)
+	if len(snapshots) != 1 || snapshots[0].Version != 1 {
+		t.Errorf("Did not list expected snapshots. Got: %v", snapshots)
+	}
+
+	pinnedRequest, pinnedRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/snapshot_model/predict?version=1", bytes.NewBuffer(badObservationJSON))
+	if pinnedRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", pinnedRequestErr)
+	}
+	prediction := Prediction{}
+	_ = unmarshalJSONResponse(t, pinnedRequest, http.StatusOK, &prediction)
+	if _, ok := prediction["second"]; ok || len(prediction) != 1 {
+		t.Errorf("Pinned prediction did not use the snapshot. Got: %v", prediction)
+	}
+
+	restoreRequest, restoreRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/snapshot_model/snapshots/before/restore", nil)
+	if restoreRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", restoreRequestErr)
+	}
+	restoredModel := &Model{}
+	_ = unmarshalJSONResponse(t, restoreRequest, http.StatusOK, restoredModel)
+	if !reflect.DeepEqual(snapshotModel, restoredModel) {
+		t.Errorf("Restored model (%v) did not match expected model (%v).", restoredModel, snapshotModel)
+	}
+
+	missingRequest, missingRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/snapshot_model/snapshots/7/restore", nil)
+	if missingRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", missingRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, missingRequest, http.StatusNotFound, &Model{})
+
+	cleanupSnapshots(t, "snapshot_model")
+	cleanupModel(t, "snapshot_model")
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	model := NewModel("prune_snapshots_model")
+	app.snapshotPolicy = SnapshotPolicy{MaxCount: 2}
+	defer func() { app.snapshotPolicy = SnapshotPolicy{} }()
+
+	for i := 0; i < 4; i++ {
+		_, err := app.createSnapshot(model, "")
+		if err != nil {
+			t.Fatalf("Failed to create snapshot: %v", err)
+		}
+	}
+
+	snapshots, err := app.loadSnapshots(model.Name, false)
+	if err != nil {
+		t.Errorf("Failed to load snapshots: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Version != 3 || snapshots[1].Version != 4 {
+		t.Errorf("Retention policy did not keep the latest snapshots. Got: %v", snapshots)
+	}
+
+	cleanupSnapshots(t, model.Name)
+}
+
+// TestFindSnapshot tests that snapshots are found by version or name without loading the others
+func TestFindSnapshot(t *testing.T) {
+	model := NewModel("find_snapshot_model")
+	for _, name := range []string{"", "named", ""} {
+		if _, err := app.createSnapshot(model, name); err != nil {
+			t.Fatalf("Failed to create snapshot: %v", err)
+		}
+	}
+	// a snapshot that can not be decoded only matters when it is the one requested
+	ioutil.WriteFile(app.snapshotPath(model.Name, 3), invalidJSON, 0644)
+
+	if snapshot, err := app.findSnapshot(model.Name, "1"); err != nil || snapshot == nil || snapshot.Model == nil {
+		t.Errorf("Did not find snapshot by version. Got: %v, %v", snapshot, err)
+	}
+	if snapshot, err := app.findSnapshot(model.Name, "named"); err != nil || snapshot == nil || snapshot.Version != 2 || snapshot.Model == nil {
+		t.Errorf("Did not find snapshot by name. Got: %v, %v", snapshot, err)
+	}
+	if snapshot, err := app.findSnapshot(model.Name, "4"); err != nil || snapshot != nil {
+		t.Errorf("Found a snapshot that does not exist. Got: %v, %v", snapshot, err)
+	}
+	if _, err := app.findSnapshot(model.Name, "3"); err == nil {
+		t.Error("Invalid snapshot did not return an error")
+	}
+
+	cleanupSnapshots(t, model.Name)
+}
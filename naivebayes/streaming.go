@@ -0,0 +1,316 @@
+package naivebayes
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxLineLength is the longest line the streaming readers accept.
+const maxLineLength = 16 * 1024 * 1024
+
+// ParseError struct.
+// A problem with one line of a training stream.
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+// Error reports the line number along with the problem.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Line %d: %s", e.Line, e.Message)
+}
+
+// ObservationReader interface.
+// Reads observations from a stream one at a time.
+// Read returns io.EOF once the stream is exhausted, and a *ParseError for a bad line.
+type ObservationReader interface {
+	Read() (*Observation, error)
+}
+
+// splitClasses splits a comma separated list of class names.
+func splitClasses(classes string) []string {
+	names := []string{}
+	for _, name := range strings.Split(classes, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// checkObservation wraps a problem with a parsed observation in a ParseError for the line.
+func checkObservation(o *Observation, line int) (*Observation, error) {
+	if err := o.ValidateTraining(); err != nil {
+		return nil, &ParseError{Line: line, Message: err.Error()}
+	}
+	return o, nil
+}
+
+// lineScanner reads non-blank lines and counts line numbers.
+type lineScanner struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newLineScanner(r io.Reader) *lineScanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	return &lineScanner{scanner: scanner}
+}
+
+// next returns the next non-blank line, or io.EOF at the end of the stream.
+func (s *lineScanner) next() (text string, err error) {
+	for s.scanner.Scan() {
+		s.line++
+		if strings.TrimSpace(s.scanner.Text()) != "" {
+			return s.scanner.Text(), nil
+		}
+	}
+	if err = s.scanner.Err(); err != nil {
+		return "", &ParseError{Line: s.line + 1, Message: err.Error()}
+	}
+	return "", io.EOF
+}
+
+/*
+	CSVReader struct.
+	Reads observations from CSV records with a column of classes and a column of text.
+	Multiple classes in one cell are separated by commas. Header skips the first record.
+*/
+type CSVReader struct {
+	ClassColumn int
+	TextColumn  int
+	Header      bool
+	tokenizer   Tokenizer
+	reader      *csv.Reader
+	started     bool
+}
+
+// NewCSVReader creates a CSVReader that splits text with the given tokenizer.
+// Records have the classes in the first column and the text in the second unless
+// the columns are changed before the first Read.
+func NewCSVReader(r io.Reader, tokenizer Tokenizer) *CSVReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return &CSVReader{ClassColumn: 0, TextColumn: 1, tokenizer: tokenizer, reader: reader}
+}
+
+// Read reads the next record.
+func (c *CSVReader) Read() (o *Observation, err error) {
+	if !c.started && c.Header {
+		if _, err = c.reader.Read(); err != nil {
+			return nil, c.csvError(err)
+		}
+	}
+	c.started = true
+	record, err := c.reader.Read()
+	if err != nil {
+		return nil, c.csvError(err)
+	}
+	line, _ := c.reader.FieldPos(0)
+	if c.ClassColumn >= len(record) || c.TextColumn >= len(record) {
+		return nil, &ParseError{Line: line, Message: fmt.Sprintf("expected at least %d columns, got %d", maxInt(c.ClassColumn, c.TextColumn)+1, len(record))}
+	}
+	return checkObservation(c.tokenizer.NewObservation(splitClasses(record[c.ClassColumn]), record[c.TextColumn]), line)
+}
+
+// csvError converts errors from the csv package into ParseErrors.
+func (c *CSVReader) csvError(err error) error {
+	if csvErr, ok := err.(*csv.ParseError); ok {
+		return &ParseError{Line: csvErr.Line, Message: csvErr.Err.Error()}
+	}
+	return err
+}
+
+// maxInt returns the larger of a and b.
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ndjsonRecord is one line of an NDJSON stream: an Observation, or classes and text
+// to be tokenized.
+type ndjsonRecord struct {
+	Observation
+	Text string
+}
+
+// NDJSONReader struct.
+// Reads one json Observation per line. Lines with a "Text" field instead of
+// "WordCounts" are tokenized.
+type NDJSONReader struct {
+	tokenizer Tokenizer
+	lines     *lineScanner
+}
+
+// NewNDJSONReader creates an NDJSONReader that splits text with the given tokenizer.
+func NewNDJSONReader(r io.Reader, tokenizer Tokenizer) *NDJSONReader {
+	return &NDJSONReader{tokenizer: tokenizer, lines: newLineScanner(r)}
+}
+
+// Read reads the next line.
+func (n *NDJSONReader) Read() (o *Observation, err error) {
+	text, err := n.lines.next()
+	if err != nil {
+		return nil, err
+	}
+	record := &ndjsonRecord{}
+	if err = json.Unmarshal([]byte(text), record); err != nil {
+		return nil, &ParseError{Line: n.lines.line, Message: err.Error()}
+	}
+	o = &record.Observation
+	if o.WordCounts == nil {
+		o = n.tokenizer.NewObservation(record.Classes, record.Text)
+		o.Weight = record.Weight
+	}
+	return checkObservation(o, n.lines.line)
+}
+
+// fastTextLabel marks the class names on a fastText line.
+const fastTextLabel = "__label__"
+
+// FastTextReader struct.
+// Reads fastText lines, where words starting with "__label__" name the classes
+// and the rest of the line is the text.
+type FastTextReader struct {
+	tokenizer Tokenizer
+	lines     *lineScanner
+}
+
+// NewFastTextReader creates a FastTextReader that splits text with the given tokenizer.
+func NewFastTextReader(r io.Reader, tokenizer Tokenizer) *FastTextReader {
+	return &FastTextReader{tokenizer: tokenizer, lines: newLineScanner(r)}
+}
+
+// Read reads the next line.
+func (f *FastTextReader) Read() (o *Observation, err error) {
+	text, err := f.lines.next()
+	if err != nil {
+		return nil, err
+	}
+	classes := []string{}
+	words := []string{}
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, fastTextLabel) {
+			classes = append(classes, strings.TrimPrefix(word, fastTextLabel))
+			continue
+		}
+		words = append(words, word)
+	}
+	return checkObservation(f.tokenizer.NewObservation(classes, strings.Join(words, " ")), f.lines.line)
+}
+
+/*
+	LinesReader struct.
+	Reads one document per line from one stream and its classes from the matching line
+	of another, comma separated. Both streams must have the same number of lines;
+	blank lines are kept so the streams stay aligned.
+*/
+type LinesReader struct {
+	tokenizer Tokenizer
+	documents *bufio.Scanner
+	labels    *bufio.Scanner
+	line      int
+}
+
+// NewLinesReader creates a LinesReader that splits text with the given tokenizer.
+func NewLinesReader(documents io.Reader, labels io.Reader, tokenizer Tokenizer) *LinesReader {
+	reader := &LinesReader{tokenizer: tokenizer, documents: bufio.NewScanner(documents), labels: bufio.NewScanner(labels)}
+	reader.documents.Buffer(make([]byte, 64*1024), maxLineLength)
+	reader.labels.Buffer(make([]byte, 64*1024), maxLineLength)
+	return reader
+}
+
+// Read reads the next document and its classes.
+func (l *LinesReader) Read() (o *Observation, err error) {
+	l.line++
+	hasDocument, hasLabels := l.documents.Scan(), l.labels.Scan()
+	if err = l.documents.Err(); err != nil {
+		return nil, &ParseError{Line: l.line, Message: "documents: " + err.Error()}
+	}
+	if err = l.labels.Err(); err != nil {
+		return nil, &ParseError{Line: l.line, Message: "labels: " + err.Error()}
+	}
+	switch {
+	case !hasDocument && !hasLabels:
+		return nil, io.EOF
+	case !hasDocument:
+		return nil, &ParseError{Line: l.line, Message: "labels continue after the last document"}
+	case !hasLabels:
+		return nil, &ParseError{Line: l.line, Message: "documents continue after the last labels"}
+	}
+	return checkObservation(l.tokenizer.NewObservation(splitClasses(l.labels.Text()), l.documents.Text()), l.line)
+}
+
+/*
+	TrainFrom trains the Model with every observation from the reader, one at a time,
+	and returns the number trained. Stops at the first error, which is returned; the
+	observations read before it stay trained.
+*/
+func (m *Model) TrainFrom(reader ObservationReader) (trained int, err error) {
+	for {
+		o, readErr := reader.Read()
+		if readErr == io.EOF {
+			return trained, nil
+		}
+		if readErr != nil {
+			return trained, readErr
+		}
+		m.Train(o)
+		trained++
+	}
+}
+
+/*
+	TrainFrom trains the Model in parallel with every observation from the reader.
+	Observations are read on a separate goroutine and handed to the workers as they
+	are read, so the stream is never held in memory. Read is only ever called from that
+	one goroutine, so the reader does not need to be safe for concurrent use, but it must
+	not be used elsewhere until TrainFrom returns. An error from the reader cancels
+	training and is returned, leaving the Model unchanged, as does cancelling the context.
+*/
+func (t *ParallelTrainer) TrainFrom(ctx context.Context, m *Model, reader ObservationReader) (trained int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	observations := make(chan *Observation)
+	readDone := make(chan struct{})
+	var readErr error
+	go func() {
+		defer close(readDone)
+		defer close(observations)
+		for {
+			o, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				readErr = err
+				cancel()
+				return
+			}
+			select {
+			case observations <- o:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	trained, err = t.Train(ctx, m, observations)
+	// stop the reader and wait for it before looking at its error
+	cancel()
+	<-readDone
+	if readErr != nil {
+		return trained, readErr
+	}
+	return trained, err
+}
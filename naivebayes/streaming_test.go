@@ -0,0 +1,99 @@
+package naivebayes
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAll reads every observation from the reader.
+func readAll(reader ObservationReader) (observations []*Observation, err error) {
+	for {
+		o, err := reader.Read()
+		if err == io.EOF {
+			return observations, nil
+		}
+		if err != nil {
+			return observations, err
+		}
+		observations = append(observations, o)
+	}
+}
+
+// TestStreamingFormats tests that every format reads the same observations
+func TestStreamingFormats(t *testing.T) {
+	tokenizer := Tokenizer{Lowercase: true}
+	expected := []*Observation{
+		tokenizer.NewObservation([]string{"spam"}, "Win money now"),
+		tokenizer.NewObservation([]string{"ham", "work"}, "Lunch meeting"),
+	}
+
+	csvReader := NewCSVReader(strings.NewReader("label,text\nspam,Win money now\n\"ham,work\",Lunch meeting\n"), tokenizer)
+	csvReader.Header = true
+	ndjson := `{"Classes": ["spam"], "Text": "Win money now"}
+
+{"Classes": ["ham", "work"], "WordCounts": {"lunch": 1, "meeting": 1}}
+`
+	readers := map[string]ObservationReader{
+		"csv":      csvReader,
+		"ndjson":   NewNDJSONReader(strings.NewReader(ndjson), tokenizer),
+		"fasttext": NewFastTextReader(strings.NewReader("__label__spam Win money now\n__label__ham __label__work Lunch meeting"), tokenizer),
+		"lines":    NewLinesReader(strings.NewReader("Win money now\nLunch meeting\n"), strings.NewReader("spam\nham,work\n"), tokenizer),
+	}
+	for format, reader := range readers {
+		observations, err := readAll(reader)
+		if err != nil {
+			t.Errorf("Failed to read %s: %v", format, err)
+		}
+		if !reflect.DeepEqual(observations, expected) {
+			t.Errorf("Did not read expected %s observations. Expected: %v, Got: %v", format, expected, observations)
+		}
+	}
+}
+
+// TestStreamingErrors tests that parse errors report the line they were found on
+func TestStreamingErrors(t *testing.T) {
+	readers := map[string]ObservationReader{
+		"csv":       NewCSVReader(strings.NewReader("spam,win\nham\n"), Tokenizer{}),
+		"csv quote": NewCSVReader(strings.NewReader("spam,win\nham,\"lunch\n"), Tokenizer{}),
+		"ndjson":    NewNDJSONReader(strings.NewReader("{\"Classes\": [\"spam\"], \"Text\": \"win\"}\n\n{\"Classes\": 1}\n"), Tokenizer{}),
+		"fasttext":  NewFastTextReader(strings.NewReader("__label__spam win\nno labels here\n"), Tokenizer{}),
+		"lines":     NewLinesReader(strings.NewReader("win\nlunch\n"), strings.NewReader("spam\n"), Tokenizer{}),
+	}
+	expectedLines := map[string]int{"csv": 2, "csv quote": 2, "ndjson": 3, "fasttext": 2, "lines": 2}
+	for format, reader := range readers {
+		_, err := readAll(reader)
+		parseErr, ok := err.(*ParseError)
+		if !ok || parseErr.Line != expectedLines[format] {
+			t.Errorf("Did not get a parse error on line %d of %s. Got: %v", expectedLines[format], format, err)
+		}
+	}
+}
+
+// TestTrainFrom tests sequential and parallel training from a stream
+func TestTrainFrom(t *testing.T) {
+	data := "__label__spam win money\n__label__ham lunch\n__label__spam win prize\n"
+	sequential := NewModel("streaming")
+	trained, err := sequential.TrainFrom(NewFastTextReader(strings.NewReader(data), sequential.Options.Tokenizer))
+	if err != nil || trained != 3 || sequential.Classes["spam"].WordCounts["win"] != 2 {
+		t.Errorf("Did not train from stream. Trained: %d, Error: %v, Got: %v", trained, err, sequential)
+	}
+
+	parallel := NewModel("streaming")
+	trainer := &ParallelTrainer{Workers: 2}
+	trained, err = trainer.TrainFrom(context.Background(), parallel, NewFastTextReader(strings.NewReader(data), parallel.Options.Tokenizer))
+	if err != nil || trained != 3 || !reflect.DeepEqual(parallel.Classes, sequential.Classes) {
+		t.Errorf("Did not train in parallel from stream. Trained: %d, Error: %v, Got: %v", trained, err, parallel)
+	}
+
+	untouched := NewModel("streaming")
+	_, err = trainer.TrainFrom(context.Background(), untouched, NewFastTextReader(strings.NewReader(data+"bad line\n"), Tokenizer{}))
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Line != 4 {
+		t.Errorf("Did not receive parse error from parallel training. Got: %v", err)
+	}
+	if untouched.ObservationCount != 0 {
+		t.Errorf("Failed parallel training changed the model: %v", untouched)
+	}
+}
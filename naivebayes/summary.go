@@ -0,0 +1,162 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ClassSummary struct.
+// The counts of a Class without its word counts.
+type ClassSummary struct {
+	Name             string
+	ObservationCount float64
+	TotalCount       float64
+}
+
+// ModelSummary struct.
+// A lightweight description of a Model that leaves out its word counts and vocabulary.
+type ModelSummary struct {
+	Name             string
+	Classes          []ClassSummary
+	ObservationCount float64
+	VocabularySize   int
+	Description      string            `json:",omitempty"`
+	Owner            string            `json:",omitempty"`
+	Source           string            `json:",omitempty"`
+	Labels           map[string]string `json:",omitempty"`
+	Created          time.Time
+	Updated          time.Time
+}
+
+// Summary returns a ModelSummary of the Model, with classes in alphabetical order.
+// The summary shares no maps with the Model.
+func (m *Model) Summary() *ModelSummary {
+	summary := &ModelSummary{
+		Name:             m.Name,
+		Classes:          []ClassSummary{},
+		ObservationCount: m.ObservationCount,
+		VocabularySize:   len(m.Vocabulary),
+		Description:      m.Metadata.Description,
+		Owner:            m.Metadata.Owner,
+		Source:           m.Metadata.Source,
+		Created:          m.Metadata.Created,
+		Updated:          m.Metadata.Updated,
+	}
+	if m.Metadata.Labels != nil {
+		summary.Labels = make(map[string]string)
+		for key, value := range m.Metadata.Labels {
+			summary.Labels[key] = value
+		}
+	}
+	for _, class := range m.Classes {
+		summary.Classes = append(summary.Classes, ClassSummary{Name: class.Name, ObservationCount: class.ObservationCount, TotalCount: class.TotalCount})
+	}
+	sort.Slice(summary.Classes, func(i, j int) bool { return summary.Classes[i].Name < summary.Classes[j].Name })
+	return summary
+}
+
+// keyCount decodes a JSON object as the number of keys it has, without keeping them.
+type keyCount int
+
+// UnmarshalJSON counts the keys of a JSON object. null counts as zero keys.
+func (c *keyCount) UnmarshalJSON(data []byte) (err error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil || token == nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("Expected a JSON object")
+	}
+	count := 0
+	for decoder.More() {
+		if _, err = decoder.Token(); err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return err
+		}
+		count++
+	}
+	*c = keyCount(count)
+	return nil
+}
+
+// storedModel is the part of a saved Model needed for its ModelSummary. Decoding into
+// it skips building the word counts and vocabulary, which are most of a model file.
+type storedModel struct {
+	Name             string
+	Classes          map[string]*ClassSummary
+	ObservationCount float64
+	Vocabulary       keyCount
+	Metadata         Metadata
+}
+
+// Summary returns the ModelSummary of the stored model.
+func (s *storedModel) Summary() *ModelSummary {
+	m := &Model{Name: s.Name, Classes: make(map[string]*Class), ObservationCount: s.ObservationCount, Metadata: s.Metadata}
+	for name, class := range s.Classes {
+		m.Classes[name] = &Class{Name: class.Name, ObservationCount: class.ObservationCount, TotalCount: class.TotalCount}
+	}
+	summary := m.Summary()
+	summary.VocabularySize = int(s.Vocabulary)
+	return summary
+}
+
+// requestFields returns the field names requested with the "fields" query parameter.
+// Both comma separated and repeated parameters are accepted.
+func requestFields(request *JSONRequest) (fields []string) {
+	for _, param := range request.Param("fields") {
+		for _, field := range strings.Split(param, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// selectFields reduces v to the given top level json fields.
+// Returns an error naming the first field that v does not have.
+func selectFields(v interface{}, fields []string) (selected map[string]json.RawMessage, err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]json.RawMessage)
+	err = json.Unmarshal(data, &all)
+	if err != nil {
+		return nil, err
+	}
+	selected = make(map[string]json.RawMessage)
+	for _, field := range fields {
+		value, ok := all[field]
+		if !ok {
+			return nil, fmt.Errorf("Unknown field: %s", field)
+		}
+		selected[field] = value
+	}
+	return selected, nil
+}
+
+// modelRepresentation returns a copy of the model or its summary, reduced to the
+// requested fields if there are any. The result shares no maps with the model, so it
+// can be encoded after the caller releases app.mu.
+func modelRepresentation(model *Model, full bool, fields []string) (v interface{}, err error) {
+	if full && len(fields) == 0 {
+		return model.Copy(), nil
+	}
+	v = model
+	if !full {
+		v = model.Summary()
+	}
+	if len(fields) == 0 {
+		return v, nil
+	}
+	return selectFields(v, fields)
+}
@@ -0,0 +1,76 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// TestModelSummary tests summarizing a model without its word counts
+func TestModelSummary(t *testing.T) {
+	model := NewModel("summary_model")
+	model.Train(NewObservationFromText([]string{"b"}, "one two"))
+	model.Train(NewObservationFromText([]string{"a"}, "two three three"))
+
+	expected := &ModelSummary{
+		Name:             "summary_model",
+		Classes:          []ClassSummary{{Name: "a", ObservationCount: 1, TotalCount: 3}, {Name: "b", ObservationCount: 1, TotalCount: 2}},
+		ObservationCount: 2,
+		VocabularySize:   3,
+		Created:          model.Metadata.Created,
+		Updated:          model.Metadata.Updated,
+	}
+	if summary := model.Summary(); !reflect.DeepEqual(expected, summary) {
+		t.Errorf("Summary (%v) did not match expected summary (%v).", summary, expected)
+	}
+
+	// a saved model can be summarized without decoding its word counts
+	data, _ := json.Marshal(model)
+	stored := &storedModel{}
+	if err := json.Unmarshal(data, stored); err != nil {
+		t.Fatalf("Failed to decode stored model: %v", err)
+	}
+	if summary := stored.Summary(); !reflect.DeepEqual(expected, summary) {
+		t.Errorf("Stored summary (%v) did not match expected summary (%v).", summary, expected)
+	}
+}
+
+func TestListModelSummaries(t *testing.T) {
+	retrieveRequest, retrieveRequestErr := http.NewRequest(http.MethodGet, server.URL+"/models", nil)
+	if retrieveRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", retrieveRequestErr)
+	}
+	summaries := []*ModelSummary{}
+	_ = unmarshalJSONResponse(t, retrieveRequest, http.StatusOK, &summaries)
+	if len(summaries) != 2 || summaries[1].Name != "test_model" || summaries[1].VocabularySize != 4 || len(summaries[1].Classes) != 2 {
+		t.Errorf("Did not receive expected model summaries. Got: %v", summaries)
+	}
+
+	fieldsRequest, fieldsRequestErr := http.NewRequest(http.MethodGet, server.URL+"/models?fields=Name,ObservationCount", nil)
+	if fieldsRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", fieldsRequestErr)
+	}
+	selected := []map[string]interface{}{}
+	_ = unmarshalJSONResponse(t, fieldsRequest, http.StatusOK, &selected)
+	expected := []map[string]interface{}{{"Name": "empty_model", "ObservationCount": 0.0}, {"Name": "test_model", "ObservationCount": 2.0}}
+	if !reflect.DeepEqual(expected, selected) {
+		t.Errorf("Selected fields (%v) did not match expected fields (%v).", selected, expected)
+	}
+
+	unknownRequest, unknownRequestErr := http.NewRequest(http.MethodGet, server.URL+"/models?fields=Nope", nil)
+	if unknownRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", unknownRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, unknownRequest, http.StatusBadRequest, &map[string]interface{}{})
+
+	summaryRequest, summaryRequestErr := http.NewRequest(http.MethodGet, server.URL+"/model/test_model?summary=1", nil)
+	if summaryRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", summaryRequestErr)
+	}
+	summary := &ModelSummary{}
+	_ = unmarshalJSONResponse(t, summaryRequest, http.StatusOK, summary)
+	if summary.Name != "test_model" || summary.ObservationCount != 2 {
+		t.Errorf("Did not receive expected model summary. Got: %v", summary)
+	}
+}
@@ -0,0 +1,83 @@
+package naivebayes
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+	Tokenizer struct.
+	Splits text into words on whitespace. The zero value keeps every word as is.
+	Lowercase folds words to lower case, StripPunctuation trims punctuation from
+	the ends of words, words shorter than MinLength are dropped and so are StopWords.
+*/
+type Tokenizer struct {
+	Lowercase        bool
+	StripPunctuation bool
+	MinLength        int      `json:",omitempty"`
+	StopWords        []string `json:",omitempty"`
+}
+
+// Copy returns a copy of the Tokenizer that shares no slices with the original.
+func (t Tokenizer) Copy() Tokenizer {
+	if t.StopWords != nil {
+		t.StopWords = append([]string{}, t.StopWords...)
+	}
+	return t
+}
+
+// Equal reports whether both Tokenizers split text into the same words.
+// Stop words may be listed in any order.
+func (t Tokenizer) Equal(other Tokenizer) bool {
+	if t.Lowercase != other.Lowercase || t.StripPunctuation != other.StripPunctuation || t.MinLength != other.MinLength {
+		return false
+	}
+	stopWords := make(map[string]bool)
+	for _, word := range t.StopWords {
+		stopWords[word] = true
+	}
+	otherStopWords := make(map[string]bool)
+	for _, word := range other.StopWords {
+		if !stopWords[word] {
+			return false
+		}
+		otherStopWords[word] = true
+	}
+	return len(stopWords) == len(otherStopWords)
+}
+
+// Tokenize splits the text into words according to the Tokenizer settings.
+func (t Tokenizer) Tokenize(text string) (words []string) {
+	stopWords := make(map[string]bool)
+	for _, word := range t.StopWords {
+		stopWords[word] = true
+	}
+	words = []string{}
+	for _, word := range strings.Fields(text) {
+		if t.StripPunctuation {
+			word = strings.TrimFunc(word, unicode.IsPunct)
+		}
+		if t.Lowercase {
+			word = strings.ToLower(word)
+		}
+		if word == "" || len([]rune(word)) < t.MinLength || stopWords[word] {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// NewObservation creates an observation from the word counts of the tokenized text.
+func (t Tokenizer) NewObservation(classes []string, text string) *Observation {
+	counts := make(map[string]int)
+	for _, word := range t.Tokenize(text) {
+		counts[word]++
+	}
+	return &Observation{Classes: classes, WordCounts: counts}
+}
+
+// NewObservation creates an observation from text using the Model's tokenizer.
+func (m *Model) NewObservation(classes []string, text string) *Observation {
+	return m.Options.Tokenizer.NewObservation(classes, text)
+}
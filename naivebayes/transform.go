@@ -0,0 +1,78 @@
+package naivebayes
+
+import "math"
+
+/*
+	TransformOptions struct.
+	Re-weights the word counts of each observation before it is trained or predicted,
+	following Rennie et al., "Tackling the poor assumptions of naive Bayes text classifiers".
+	* LogTermFrequency - counts become log(1 + count), so repeated words count for less
+	* InverseDocumentFrequency - counts are scaled by log((1 + N) / (1 + n)) + 1, where N is
+	  the number of observations the Model has been trained with and n the number that
+	  contained the word, so common words count for less
+	* NormalizeLength - counts are scaled so their squares sum to one, so long
+	  observations count for no more than short ones
+	The transformations are applied in that order, after hashing.
+*/
+type TransformOptions struct {
+	LogTermFrequency         bool `json:",omitempty"`
+	InverseDocumentFrequency bool `json:",omitempty"`
+	NormalizeLength          bool `json:",omitempty"`
+}
+
+// Copy returns a copy of the TransformOptions.
+func (o *TransformOptions) Copy() *TransformOptions {
+	c := *o
+	return &c
+}
+
+// inverseDocumentFrequency weights a word by how few of the Model's observations contained it.
+func (m *Model) inverseDocumentFrequency(word string) float64 {
+	documents := 0.0
+	for _, class := range m.Classes {
+		documents += class.documentCount(word)
+	}
+	return math.Log((1+m.ObservationCount)/(1+documents)) + 1
+}
+
+/*
+	features returns the counts the Model uses for the words of an observation:
+	words are replaced by their buckets if the Model hashes features, and counts are
+	transformed by the Model's TransformOptions.
+*/
+func (m *Model) features(o *Observation) (counts map[string]float64) {
+	counts = make(map[string]float64)
+	for word, count := range o.WordCounts {
+		counts[word] = float64(count)
+	}
+	if m.Options.Hashing != nil {
+		counts = m.Options.Hashing.hash(counts)
+	}
+
+	transform := m.Options.Transform
+	if transform == nil {
+		return counts
+	}
+	for word, count := range counts {
+		if transform.LogTermFrequency {
+			count = math.Log1p(count)
+		}
+		if transform.InverseDocumentFrequency {
+			count *= m.inverseDocumentFrequency(word)
+		}
+		counts[word] = count
+	}
+	if transform.NormalizeLength {
+		length := 0.0
+		for _, count := range counts {
+			length += count * count
+		}
+		if length > 0 {
+			length = math.Sqrt(length)
+			for word := range counts {
+				counts[word] /= length
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,81 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// TestTransformFeatures tests each transformation against hand calculated values
+func TestTransformFeatures(t *testing.T) {
+	model := NewModel("transform")
+	model.Train(NewObservationFromText([]string{"spam"}, "the win"))
+	model.Train(NewObservationFromText([]string{"spam"}, "the prize"))
+	model.Train(NewObservationFromText([]string{"ham"}, "the lunch"))
+	observation := &Observation{WordCounts: map[string]int{"the": 3, "win": 1}}
+
+	model.Options.Transform = &TransformOptions{LogTermFrequency: true}
+	if counts := model.features(observation); counts["the"] != math.Log(4) || counts["win"] != math.Log(2) {
+		t.Errorf("Did not get log term frequencies. Got: %v", counts)
+	}
+
+	model.Options.Transform = &TransformOptions{InverseDocumentFrequency: true}
+	if counts := model.features(observation); counts["the"] != 3 || math.Abs(counts["win"]-(math.Log(2)+1)) > 1e-12 {
+		t.Errorf("Did not get inverse document frequency weights. Got: %v", counts)
+	}
+
+	model.Options.Transform = &TransformOptions{NormalizeLength: true}
+	if counts := model.features(observation); math.Abs(counts["the"]-3/math.Sqrt(10)) > 1e-12 || math.Abs(counts["win"]-1/math.Sqrt(10)) > 1e-12 {
+		t.Errorf("Did not get length normalized counts. Got: %v", counts)
+	}
+}
+
+// TestTransformTraining tests that long observations no longer dominate a normalized model
+func TestTransformTraining(t *testing.T) {
+	model := NewModel("transform")
+	model.Options.Transform = &TransformOptions{LogTermFrequency: true, InverseDocumentFrequency: true, NormalizeLength: true}
+	model.Train(NewObservationFromText([]string{"long"}, "spam spam spam spam spam spam spam spam"))
+	model.Train(NewObservationFromText([]string{"short"}, "ham"))
+
+	if math.Abs(model.Classes["long"].TotalCount-1) > 1e-12 || math.Abs(model.Classes["short"].TotalCount-1) > 1e-12 {
+		t.Errorf("Observations were not normalized to the same length. Got: %v, %v", model.Classes["long"], model.Classes["short"])
+	}
+	if err := model.Validate(); err != nil {
+		t.Errorf("Transformed model failed validation: %v", err)
+	}
+
+	posterior := model.Posterior(NewObservationFromText([]string{}, "spam"))
+	if class, _ := posterior.BestFit(); class != "long" {
+		t.Errorf("Did not predict expected class. Got: %v", posterior)
+	}
+	explanations := model.Explain(NewObservationFromText([]string{}, "spam spam"))
+	if math.Abs(explanations[0].Words[0].Count-1) > 1e-12 {
+		t.Errorf("Explanation did not report the transformed count. Got: %v", explanations[0].Words)
+	}
+}
+
+func TestTransformedModelRequests(t *testing.T) {
+	// setup
+	transformModelJSON := []byte(`{"Name": "transform_model", "Classes": {}, "Vocabulary": {}, "Options": {"Transform": {"LogTermFrequency": true, "NormalizeLength": true}}}`)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(transformModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{"class_a"}, "a a a test"))
+	trainRequest, trainRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/transform_model/train", bytes.NewBuffer(observationJSON))
+	if trainRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", trainRequestErr)
+	}
+	trained := &Model{}
+	_ = unmarshalJSONResponse(t, trainRequest, http.StatusOK, trained)
+	expected := math.Log(4) / math.Sqrt(math.Log(4)*math.Log(4)+math.Log(2)*math.Log(2))
+	if trained.Options.Transform == nil || math.Abs(trained.Classes["class_a"].WordCounts["a"]-expected) > 1e-12 {
+		t.Errorf("Observation was not transformed. Got: %v", trained.Classes["class_a"])
+	}
+
+	cleanupModel(t, "transform_model")
+}
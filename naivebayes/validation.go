@@ -0,0 +1,277 @@
+package naivebayes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxModelNameLength is the longest name a model can be given.
+const MaxModelNameLength = 128
+
+// countTolerance is the relative error allowed when comparing weighted counts,
+// which are summed in a different order than they were added.
+const countTolerance = 1e-9
+
+// reservedModelNames are names that can not be used for models because they have a
+// special meaning to the filesystem on some platforms.
+var reservedModelNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// isNameChar reports whether c may appear in a model name.
+func isNameChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '.'
+}
+
+/*
+	ValidateModelName checks that name is safe to use as a model name.
+	Names must be 1 to MaxModelNameLength characters of letters, digits, '_', '-' and '.',
+	must start with a letter or digit and must not be a reserved name.
+	This keeps names from escaping the model storage directory.
+*/
+func ValidateModelName(name string) (err error) {
+	if name == "" {
+		return fmt.Errorf("Invalid model name. Name is required.")
+	}
+	if len(name) > MaxModelNameLength {
+		return fmt.Errorf("Invalid model name. Name must be at most %d characters.", MaxModelNameLength)
+	}
+	for i, c := range name {
+		if !isNameChar(c) {
+			return fmt.Errorf("Invalid model name %q. Names may only contain letters, digits, '_', '-' and '.'.", name)
+		}
+		if i == 0 && (c == '_' || c == '-' || c == '.') {
+			return fmt.Errorf("Invalid model name %q. Names must start with a letter or digit.", name)
+		}
+	}
+	base := strings.ToLower(strings.SplitN(name, ".", 2)[0])
+	if reservedModelNames[base] {
+		return fmt.Errorf("Invalid model name %q. Name is reserved.", name)
+	}
+	return nil
+}
+
+// FieldError struct.
+// Describes a problem with a single field of a payload.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError struct.
+// Collects every problem found while validating a payload.
+// Marshals to json so clients receive the field-level details.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error joins the field errors into a single message.
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+	return "Validation failed. " + strings.Join(messages, "; ")
+}
+
+// add records a problem with the given field.
+func (e *ValidationError) add(field string, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// result returns e if any problems were recorded, otherwise nil.
+// Fields are sorted so the same payload always produces the same error.
+func (e *ValidationError) result() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	sort.SliceStable(e.Fields, func(i, j int) bool { return e.Fields[i].Field < e.Fields[j].Field })
+	return e
+}
+
+/*
+	Validate checks that the Model is internally consistent.
+	Counts must be non-negative, each class TotalCount must match its WordCounts,
+	each class must be stored under its own name and every word a class has seen
+	must be in the Vocabulary.
+	Returns a *ValidationError listing every problem found.
+*/
+func (m *Model) Validate() (err error) {
+	v := &ValidationError{}
+	if nameErr := ValidateModelName(m.Name); nameErr != nil {
+		v.add("Name", "%v", nameErr)
+	}
+	if m.ObservationCount < 0 {
+		v.add("ObservationCount", "must not be negative")
+	}
+	if m.Vocabulary == nil {
+		v.add("Vocabulary", "is required")
+	}
+	if m.Classes == nil {
+		v.add("Classes", "is required")
+	}
+	if m.Options.Smoothing < 0 {
+		v.add("Options.Smoothing", "must not be negative")
+	}
+	if m.Options.MultiLabel != nil {
+		if m.Options.MultiLabel.Threshold < 0 || m.Options.MultiLabel.Threshold > 1 {
+			v.add("Options.MultiLabel.Threshold", "must be between 0 and 1")
+		}
+		for className, threshold := range m.Options.MultiLabel.Thresholds {
+			if threshold < 0 || threshold > 1 {
+				v.add("Options.MultiLabel.Thresholds."+className, "must be between 0 and 1")
+			}
+		}
+	}
+	if m.Options.Decay != nil {
+		if m.Options.Decay.HalfLife <= 0 {
+			v.add("Options.Decay.HalfLife", "must be positive")
+		}
+		if m.Options.Decay.MinCount < 0 {
+			v.add("Options.Decay.MinCount", "must not be negative")
+		}
+	}
+	if m.Options.Hashing != nil && m.Options.Hashing.Buckets < 1 {
+		v.add("Options.Hashing.Buckets", "must be positive")
+	}
+	if m.Options.Pruning != nil {
+		m.Options.Pruning.validate(v, "Options.Pruning")
+	}
+	if m.Options.Priors != nil {
+		m.Options.Priors.validate(v, "Options.Priors")
+	}
+	if m.Calibration != nil && m.Calibration.Method != CalibrationPlatt && m.Calibration.Method != CalibrationIsotonic {
+		v.add("Calibration.Method", "must be %q or %q", CalibrationPlatt, CalibrationIsotonic)
+	}
+	for key := range m.Metadata.Labels {
+		if key == "" {
+			v.add("Metadata.Labels", "label keys must not be empty")
+		}
+	}
+
+	missing := make(map[string]bool)
+	for key, class := range m.Classes {
+		field := "Classes." + key
+		if class == nil {
+			v.add(field, "must not be null")
+			continue
+		}
+		if class.Name != key {
+			v.add(field+".Name", "must match the class key %q", key)
+		}
+		if class.ObservationCount < 0 {
+			v.add(field+".ObservationCount", "must not be negative")
+		}
+		if class.ObservationCount > m.ObservationCount*(1+countTolerance) {
+			v.add(field+".ObservationCount", "must not be more than the model ObservationCount")
+		}
+		if class.WordCounts == nil {
+			v.add(field+".WordCounts", "is required")
+		}
+		total := 0.0
+		for word, count := range class.WordCounts {
+			if count < 0 {
+				v.add(field+".WordCounts."+word, "must not be negative")
+			}
+			if _, ok := m.Vocabulary[word]; !ok && m.Vocabulary != nil && !missing[word] {
+				v.add("Vocabulary."+word, "is missing but counted by class %q", key)
+				missing[word] = true
+			}
+			if m.Options.Hashing != nil && m.Options.Hashing.Buckets > 0 && !m.Options.Hashing.validBucket(word) {
+				v.add(field+".WordCounts."+word, "is not one of the %d hashing buckets", m.Options.Hashing.Buckets)
+			}
+			total += count
+		}
+		if math.Abs(class.TotalCount-total) > countTolerance*math.Max(1, total) {
+			v.add(field+".TotalCount", "is %g but WordCounts sum to %g", class.TotalCount, total)
+		}
+		for word, count := range class.DocumentCounts {
+			if _, ok := class.WordCounts[word]; !ok {
+				v.add(field+".DocumentCounts."+word, "is not counted in WordCounts")
+			}
+			if count < 0 || count > class.ObservationCount*(1+countTolerance) {
+				v.add(field+".DocumentCounts."+word, "must be between zero and the class ObservationCount")
+			}
+		}
+	}
+	return v.result()
+}
+
+/*
+	Recompute rebuilds the derived fields of the Model from its class word counts.
+	Missing maps are created, class names are taken from their keys, TotalCounts are
+	summed and the Vocabulary is rebuilt. ObservationCount can not be derived when
+	observations belong to several classes, so it is only raised to cover the largest class.
+*/
+func (m *Model) Recompute() {
+	if m.Classes == nil {
+		m.Classes = make(map[string]*Class)
+	}
+	m.Vocabulary = make(map[string]int)
+	for key, class := range m.Classes {
+		if class == nil {
+			class = NewClass(key)
+			m.Classes[key] = class
+		}
+		class.Name = key
+		if class.WordCounts == nil {
+			class.WordCounts = make(map[string]float64)
+		}
+		class.TotalCount = 0
+		for word, count := range class.WordCounts {
+			class.TotalCount += count
+			m.Vocabulary[word] = 1
+		}
+		for word := range class.DocumentCounts {
+			if _, ok := class.WordCounts[word]; !ok {
+				delete(class.DocumentCounts, word)
+			}
+		}
+		if class.ObservationCount > m.ObservationCount {
+			m.ObservationCount = class.ObservationCount
+		}
+	}
+}
+
+// Validate checks that the Observation has usable class names and word counts.
+func (o *Observation) Validate() (err error) {
+	v := &ValidationError{}
+	seen := make(map[string]bool)
+	for i, className := range o.Classes {
+		if className == "" {
+			v.add(fmt.Sprintf("Classes.%d", i), "must not be empty")
+		}
+		if seen[className] {
+			v.add(fmt.Sprintf("Classes.%d", i), "duplicates class %q", className)
+		}
+		seen[className] = true
+	}
+	for word, count := range o.WordCounts {
+		if count < 0 {
+			v.add("WordCounts."+word, "must not be negative")
+		}
+	}
+	if o.Weight < 0 || math.IsNaN(o.Weight) || math.IsInf(o.Weight, 0) {
+		v.add("Weight", "must be a positive number")
+	}
+	return v.result()
+}
+
+// ValidateTraining checks that the Observation can be used to train a model.
+// In addition to Validate it requires at least one class.
+func (o *Observation) ValidateTraining() (err error) {
+	err = o.Validate()
+	if len(o.Classes) > 0 {
+		return err
+	}
+	v, ok := err.(*ValidationError)
+	if !ok {
+		v = &ValidationError{}
+	}
+	v.add("Classes", "at least one class is required for training")
+	return v
+}
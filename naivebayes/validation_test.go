@@ -0,0 +1,133 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestValidateModelName tests accepted and rejected model names
+func TestValidateModelName(t *testing.T) {
+	valid := []string{"a", "test_model", "model-2.v1", strings.Repeat("x", MaxModelNameLength)}
+	for _, name := range valid {
+		if err := ValidateModelName(name); err != nil {
+			t.Errorf("Valid model name %q was rejected: %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "..", "../../etc/x", "a/b", ".hidden", "-flag", "space name", "CON", "nul.json", strings.Repeat("x", MaxModelNameLength+1)}
+	for _, name := range invalid {
+		if err := ValidateModelName(name); err == nil {
+			t.Errorf("Invalid model name %q was accepted", name)
+		}
+	}
+}
+
+// TestInvalidModelNameRequests tests that every kind of request rejects unsafe names
+func TestInvalidModelNameRequests(t *testing.T) {
+	traversalModel := NewModel("../../traversal_model")
+	traversalModelJSON, _ := json.Marshal(traversalModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(traversalModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, createRequest, http.StatusBadRequest, &Model{})
+	if _, statErr := os.Stat("traversal_model.json"); !os.IsNotExist(statErr) {
+		os.Remove("traversal_model.json")
+		t.Error("Model with an unsafe name was saved outside the model directory")
+	}
+
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{"a"}, "some text"))
+	for _, endpoint := range []string{"/model/.hidden/train", "/model/.hidden/predict", "/model/.hidden/snapshots"} {
+		request, requestErr := http.NewRequest(http.MethodPost, server.URL+endpoint, bytes.NewBuffer(observationJSON))
+		if requestErr != nil {
+			t.Errorf("Failed to generate request: %v", requestErr)
+		}
+		_ = unmarshalJSONResponse(t, request, http.StatusBadRequest, &Model{})
+	}
+}
+
+// TestModelValidate tests that inconsistent models are reported field by field and
+// that Recompute repairs derived totals
+func TestModelValidate(t *testing.T) {
+	model := NewModel("validate_model")
+	model.Train(NewObservationFromText([]string{"a"}, "some text"))
+	if err := model.Validate(); err != nil {
+		t.Errorf("Trained model failed validation: %v", err)
+	}
+
+	model.Classes["a"].TotalCount = 7
+	model.Classes["a"].WordCounts["typo"] = 1
+	model.Classes["b"] = nil
+	validateErr, ok := model.Validate().(*ValidationError)
+	if !ok {
+		t.Fatalf("Inconsistent model did not return a ValidationError")
+	}
+	expectedFields := []string{"Classes.a.TotalCount", "Classes.b", "Vocabulary.typo"}
+	if len(validateErr.Fields) != len(expectedFields) {
+		t.Fatalf("Did not get expected field errors. Expected: %v, Got: %v", expectedFields, validateErr.Fields)
+	}
+	for i, field := range expectedFields {
+		if validateErr.Fields[i].Field != field {
+			t.Errorf("Did not get expected field error. Expected: %s, Got: %v", field, validateErr.Fields[i])
+		}
+	}
+
+	model.Recompute()
+	if err := model.Validate(); err != nil {
+		t.Errorf("Recomputed model failed validation: %v", err)
+	}
+	if model.Classes["a"].TotalCount != 3 || model.Vocabulary["typo"] != 1 {
+		t.Errorf("Recompute did not rebuild totals and vocabulary: %v", model)
+	}
+}
+
+// TestObservationValidate tests observation payload checks
+func TestObservationValidate(t *testing.T) {
+	valid := NewObservationFromText([]string{"a"}, "some text")
+	if err := valid.ValidateTraining(); err != nil {
+		t.Errorf("Valid observation failed validation: %v", err)
+	}
+
+	unlabeled := NewObservationFromText([]string{}, "some text")
+	if err := unlabeled.Validate(); err != nil {
+		t.Errorf("Unlabeled observation failed validation: %v", err)
+	}
+	if err := unlabeled.ValidateTraining(); err == nil {
+		t.Error("Unlabeled observation was accepted for training")
+	}
+
+	invalid := &Observation{Classes: []string{"a", "a", ""}, WordCounts: map[string]int{"text": -1}}
+	if err, ok := invalid.Validate().(*ValidationError); !ok || len(err.Fields) != 3 {
+		t.Errorf("Did not get expected field errors. Got: %v", err)
+	}
+}
+
+// TestCreateInvalidModel tests that inconsistent models are rejected with details
+func TestCreateInvalidModel(t *testing.T) {
+	payload := []byte(`{"Name": "invalid_counts", "Classes": {"a": {"Name": "a", "ObservationCount": 1, "WordCounts": {"x": 2}, "TotalCount": 5}}, "ObservationCount": 1}`)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(payload))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	validateErr := &ValidationError{}
+	_ = unmarshalJSONResponse(t, createRequest, http.StatusBadRequest, validateErr)
+	if len(validateErr.Fields) != 2 {
+		t.Errorf("Did not receive expected field errors. Got: %v", validateErr.Fields)
+	}
+
+	recomputeRequest, recomputeRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model?recompute=1", bytes.NewBuffer(payload))
+	if recomputeRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", recomputeRequestErr)
+	}
+	recomputed := &Model{}
+	_ = unmarshalJSONResponse(t, recomputeRequest, http.StatusOK, recomputed)
+	if recomputed.Classes["a"].TotalCount != 2 || recomputed.Vocabulary["x"] != 1 {
+		t.Errorf("Model was not recomputed: %v", recomputed)
+	}
+
+	cleanupModel(t, "invalid_counts")
+}
@@ -0,0 +1,271 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReloadResult struct.
+// Lists the models that changed during a scan of the model directory.
+type ReloadResult struct {
+	Loaded   []string
+	Reloaded []string
+	Unloaded []string
+}
+
+/*
+	modelFile records the state of a model file the last time it was saved or scanned,
+	with a summary of the model it holds for listing models that are not loaded.
+	saves counts the times the app saved the file, so a save is noticed even when it
+	leaves the modification time unchanged on filesystems with coarse timestamps.
+*/
+type modelFile struct {
+	modelName string
+	modTime   time.Time
+	size      int64
+	saves     int
+	summary   *ModelSummary
+}
+
+// changed reports whether the file described by info differs from the recorded state.
+func (f *modelFile) changed(info os.FileInfo) bool {
+	return !f.modTime.Equal(info.ModTime()) || f.size != info.Size()
+}
+
+// saveModel saves the given model to its storage path, and records the new file state
+// so the watcher does not reload a change the app made itself.
+func (app *NaiveBayesApp) saveModel(model *Model) (err error) {
+	lock := app.modelLock(model.Name)
+	lock.Lock()
+	defer lock.Unlock()
+	path := app.modelPath(model.Name)
+	err = SaveToFile(path, model, json.Marshal)
+	if err != nil {
+		return err
+	}
+	if info, statErr := os.Stat(path); statErr == nil {
+		app.filesMu.Lock()
+		state := &modelFile{modelName: model.Name, modTime: info.ModTime(), size: info.Size(), summary: model.Summary()}
+		if previous, ok := app.files[info.Name()]; ok {
+			state.saves = previous.saves + 1
+		}
+		app.files[info.Name()] = state
+		app.filesMu.Unlock()
+	}
+	return nil
+}
+
+/*
+	reloadModels scans app.modelDir and brings app.models in line with it.
+	New files are loaded, modified files are reloaded and deleted files are unloaded.
+	Each model is fully read before it replaces the loaded copy, so requests
+	never see a partially loaded model.
+*/
+func (app *NaiveBayesApp) reloadModels() (result *ReloadResult, err error) {
+	changed, removed, err := app.scanModels()
+	if err != nil {
+		return nil, err
+	}
+	return app.swapModels(changed, removed), nil
+}
+
+// loadedFile is a model read from disk by scanModels, with the state of its file
+// at the time it was read.
+type loadedFile struct {
+	fileName string
+	state    *modelFile
+	model    *Model
+}
+
+// scanModels reads the model files that changed since the last scan, and returns the
+// names of models whose files were deleted. Nothing is swapped into app.models.
+// Files are read without holding app.filesMu, so saves are not held up by a scan.
+func (app *NaiveBayesApp) scanModels() (changed []*loadedFile, removed []string, err error) {
+	files, err := ioutil.ReadDir(app.modelDir)
+	if err != nil {
+		log.Printf("Failed to load all models from dir: '%s' with error: '%s'", app.modelDir, err)
+		return nil, nil, err
+	}
+
+	modified := []os.FileInfo{}
+	previous := make(map[string]*modelFile)
+	seen := make(map[string]bool)
+	app.filesMu.Lock()
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		seen[file.Name()] = true
+		state, known := app.files[file.Name()]
+		if known && !state.changed(file) {
+			continue
+		}
+		modified = append(modified, file)
+		previous[file.Name()] = state
+	}
+	for fileName, state := range app.files {
+		if seen[fileName] {
+			continue
+		}
+		delete(app.files, fileName)
+		if state.modelName != "" {
+			removed = append(removed, state.modelName)
+		}
+	}
+	app.filesMu.Unlock()
+
+	for _, file := range modified {
+		state, model := app.readModelFile(file)
+		app.filesMu.Lock()
+		current := app.files[file.Name()]
+		// a file the app saved while it was read already has a newer recorded state
+		saved := current != previous[file.Name()]
+		if !saved {
+			if current != nil {
+				state.saves = current.saves
+			}
+			app.files[file.Name()] = state
+		}
+		app.filesMu.Unlock()
+		if !saved && model != nil {
+			changed = append(changed, &loadedFile{fileName: file.Name(), state: state, model: model})
+		}
+	}
+	return changed, removed, nil
+}
+
+/*
+	readModelFile reads the state of a model file.
+	Models are named by their file, so a file can never replace or unload another model.
+	The model is only decoded in full, and returned, if the app keeps it in memory.
+	Otherwise only the fields needed for its summary are decoded.
+*/
+func (app *NaiveBayesApp) readModelFile(file os.FileInfo) (state *modelFile, model *Model) {
+	state = &modelFile{modTime: file.ModTime(), size: file.Size()}
+	modelName, unescapeErr := url.PathUnescape(strings.TrimSuffix(file.Name(), ".json"))
+	if unescapeErr != nil || ValidateModelName(modelName) != nil {
+		return state, nil
+	}
+	state.modelName = modelName
+	path := app.modelDir + "/" + file.Name()
+
+	// lazily loaded models are only kept in memory when requested or already cached
+	if app.lazy && !app.models.contains(modelName) {
+		stored := &storedModel{}
+		loadErr := LoadFromFile(path, stored, json.Unmarshal)
+		if loadErr != nil {
+			log.Printf("Failed to load model from file: '%s' with error: '%s'", file.Name(), loadErr)
+			return state, nil
+		}
+		if stored.Name != modelName {
+			log.Printf("Failed to load model from file: '%s' with error: 'file contains model %s'", file.Name(), stored.Name)
+			return state, nil
+		}
+		state.summary = stored.Summary()
+		return state, nil
+	}
+
+	model = &Model{}
+	loadErr := LoadFromFile(path, model, json.Unmarshal)
+	if loadErr != nil {
+		log.Printf("Failed to load model from file: '%s' with error: '%s'", file.Name(), loadErr)
+		return state, nil
+	}
+	if model.Name != modelName {
+		log.Printf("Failed to load model from file: '%s' with error: 'file contains model %s'", file.Name(), model.Name)
+		return state, nil
+	}
+	// models written by other processes are checked like models created through the app
+	if validateErr := model.Validate(); validateErr != nil {
+		log.Printf("Failed to load model from file: '%s' with error: '%s'", file.Name(), validateErr)
+		return state, nil
+	}
+	state.summary = model.Summary()
+	return state, model
+}
+
+/*
+	swapModels replaces and unloads models found by scanModels.
+	Files are checked again under app.mu, and any that the app saved or that were
+	written or recreated since the scan are skipped, so a model trained in the meantime
+	is never replaced by the older copy that was read. Files changed by other processes
+	are picked up by the next scan.
+*/
+func (app *NaiveBayesApp) swapModels(changed []*loadedFile, removed []string) (result *ReloadResult) {
+	result = &ReloadResult{Loaded: []string{}, Reloaded: []string{}, Unloaded: []string{}}
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	for _, loaded := range changed {
+		model := loaded.model
+		app.filesMu.Lock()
+		current := app.files[loaded.fileName]
+		app.filesMu.Unlock()
+		if current == nil || current.saves != loaded.state.saves {
+			continue
+		}
+		if info, statErr := os.Stat(app.modelDir + "/" + loaded.fileName); statErr != nil || loaded.state.changed(info) {
+			continue
+		}
+		if app.models.contains(model.Name) {
+			log.Printf("Reloaded model: %s from file.", model.Name)
+			result.Reloaded = append(result.Reloaded, model.Name)
+		} else {
+			log.Printf("Loaded model: %s from file.", model.Name)
+			result.Loaded = append(result.Loaded, model.Name)
+		}
+		app.models.add(model)
+	}
+	for _, modelName := range removed {
+		if !app.models.contains(modelName) {
+			continue
+		}
+		if _, statErr := os.Stat(app.modelPath(modelName)); statErr == nil {
+			continue
+		}
+		app.models.remove(modelName)
+		log.Printf("Unloaded model: %s, file was removed.", modelName)
+		result.Unloaded = append(result.Unloaded, modelName)
+	}
+	return result
+}
+
+// watchModels polls app.modelDir for changes every interval until Close is called.
+func (app *NaiveBayesApp) watchModels(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			app.reloadModels()
+		case <-app.done:
+			return
+		}
+	}
+}
+
+// Close stops any background work started by the app.
+func (app *NaiveBayesApp) Close() {
+	close(app.done)
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   reloadAllModels forces a scan of the model directory.
+   * POST /admin/reload - Load new, reload modified and unload deleted model files
+*/
+func (app *NaiveBayesApp) reloadAllModels(request *JSONRequest) *JSONResponse {
+	result, err := app.reloadModels()
+	if err != nil {
+		return &JSONResponse{Error: err, Code: http.StatusInternalServerError}
+	}
+	return &JSONResponse{Data: result, Code: http.StatusOK}
+}
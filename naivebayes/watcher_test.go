@@ -0,0 +1,160 @@
+package naivebayes
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func forceReload(t *testing.T) *ReloadResult {
+	reloadRequest, reloadRequestErr := http.NewRequest(http.MethodPost, server.URL+"/admin/reload", nil)
+	if reloadRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", reloadRequestErr)
+	}
+	result := &ReloadResult{}
+	_ = unmarshalJSONResponse(t, reloadRequest, http.StatusOK, result)
+	return result
+}
+
+func TestReloadModels(t *testing.T) {
+	path := app.modelPath("reload_model")
+	model := NewModel("reload_model")
+	saveErr := SaveToFile(path, model, json.Marshal)
+	if saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+
+	result := forceReload(t)
+	if !reflect.DeepEqual(result.Loaded, []string{"reload_model"}) {
+		t.Errorf("Expected new model to be loaded. Got: %v", result)
+	}
+	if _, ok := app.getModel("reload_model"); !ok {
+		t.Error("New model file was not loaded")
+	}
+
+	model.Train(NewObservationFromText([]string{"offline"}, "trained offline"))
+	saveErr = SaveToFile(path, model, json.Marshal)
+	if saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+	// make sure the change is visible even on filesystems with coarse timestamps
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	result = forceReload(t)
+	if !reflect.DeepEqual(result.Reloaded, []string{"reload_model"}) {
+		t.Errorf("Expected modified model to be reloaded. Got: %v", result)
+	}
+	if reloaded, _ := app.getModel("reload_model"); !reflect.DeepEqual(model, reloaded) {
+		t.Errorf("Reloaded model (%v) did not match expected model (%v).", reloaded, model)
+	}
+
+	result = forceReload(t)
+	if len(result.Loaded)+len(result.Reloaded)+len(result.Unloaded) != 0 {
+		t.Errorf("Expected no changes when files are untouched. Got: %v", result)
+	}
+
+	os.Remove(path)
+	result = forceReload(t)
+	if !reflect.DeepEqual(result.Unloaded, []string{"reload_model"}) {
+		t.Errorf("Expected deleted model to be unloaded. Got: %v", result)
+	}
+	if _, ok := app.getModel("reload_model"); ok {
+		t.Error("Deleted model file was not unloaded")
+	}
+}
+
+// TestReloadMismatchedName tests that a file holding another model's name is ignored
+func TestReloadMismatchedName(t *testing.T) {
+	target := NewModel("mismatch_target")
+	if saveErr := app.saveModel(target); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+	app.models.add(target)
+
+	path := app.modelPath("mismatch_file")
+	impostor := NewModel("mismatch_target")
+	impostor.Train(NewObservationFromText([]string{"impostor"}, "wrong file"))
+	if saveErr := SaveToFile(path, impostor, json.Marshal); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+
+	result := forceReload(t)
+	if len(result.Loaded)+len(result.Reloaded)+len(result.Unloaded) != 0 {
+		t.Errorf("Expected mismatched file to be ignored. Got: %v", result)
+	}
+	os.Remove(path)
+	result = forceReload(t)
+	if len(result.Unloaded) != 0 {
+		t.Errorf("Removing a mismatched file unloaded a model. Got: %v", result)
+	}
+	if loaded, ok := app.getModel("mismatch_target"); !ok || loaded != target {
+		t.Errorf("Target model was replaced or unloaded. Got: %v", loaded)
+	}
+	cleanupModel(t, "mismatch_target")
+}
+
+// TestReloadAfterTrain tests that a model trained between reading and swapping is not replaced
+func TestReloadAfterTrain(t *testing.T) {
+	model := NewModel("reload_train_model")
+	if saveErr := app.saveModel(model); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+	app.models.add(model)
+
+	// an older copy is written by another process and read by the watcher
+	path := app.modelPath("reload_train_model")
+	if saveErr := SaveToFile(path, NewModel("reload_train_model"), json.Marshal); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	changed, removed, scanErr := app.scanModels()
+	if scanErr != nil || len(changed) != 1 {
+		t.Fatalf("Expected modified model to be read. Got: %v, %v", changed, scanErr)
+	}
+
+	observationJSON, _ := json.Marshal(NewObservationFromText([]string{"testing"}, "trained between"))
+	trainResponse := app.trainModel(&JSONRequest{Vars: map[string]string{"modelName": "reload_train_model"}, Data: observationJSON})
+	if trainResponse.IsError() {
+		t.Fatalf("Failed to train model: %v", trainResponse.Error)
+	}
+	// with coarse timestamps the save can keep the modification time that was scanned
+	os.Chtimes(path, later, later)
+
+	result := app.swapModels(changed, removed)
+	if len(result.Reloaded) != 0 {
+		t.Errorf("Expected the older copy to be skipped. Got: %v", result)
+	}
+	if trained, _ := app.getModel("reload_train_model"); trained.ObservationCount != 1 {
+		t.Errorf("Trained model was replaced. Got: %v", trained)
+	}
+	cleanupModel(t, "reload_train_model")
+}
+
+// TestReloadInvalidModel tests that model files that fail validation are not loaded
+func TestReloadInvalidModel(t *testing.T) {
+	path := app.modelPath("reload_invalid_model")
+	model := NewModel("reload_invalid_model")
+	model.Options.Hashing = &HashingOptions{}
+	if saveErr := SaveToFile(path, model, json.Marshal); saveErr != nil {
+		t.Fatalf("Failed to save model: %v", saveErr)
+	}
+	defer os.Remove(path)
+
+	result := forceReload(t)
+	if len(result.Loaded) != 0 {
+		t.Errorf("Expected invalid model to be skipped. Got: %v", result)
+	}
+	if _, ok := app.getModel("reload_invalid_model"); ok {
+		t.Error("Invalid model file was loaded")
+	}
+
+	lazyApp := NewNaiveBayesApp(&Config{ModelDir: app.modelDir, CacheSize: 1})
+	if _, ok := lazyApp.getModel("reload_invalid_model"); ok {
+		t.Error("Lazy app loaded an invalid model file")
+	}
+}
@@ -0,0 +1,110 @@
+package naivebayes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// TestWeightedTraining tests that weights scale the counts an observation adds
+func TestWeightedTraining(t *testing.T) {
+	model := NewModel("weights")
+	model.Train(&Observation{Classes: []string{"reviewed"}, WordCounts: map[string]int{"good": 2}, Weight: 2.5})
+	model.Train(NewObservationFromText([]string{"auto"}, "good bad"))
+
+	reviewed := model.Classes["reviewed"]
+	if reviewed.ObservationCount != 2.5 || reviewed.WordCounts["good"] != 5 || reviewed.TotalCount != 5 || model.ObservationCount != 3.5 {
+		t.Errorf("Weight was not applied to counts. Got: %v, %v", model, reviewed)
+	}
+	if err := model.Validate(); err != nil {
+		t.Errorf("Weighted model failed validation: %v", err)
+	}
+
+	negative := &Observation{Classes: []string{"auto"}, WordCounts: map[string]int{"bad": 1}, Weight: -1}
+	if err := negative.ValidateTraining(); err == nil {
+		t.Error("Negative weight was accepted")
+	}
+}
+
+// TestUntrain tests that Untrain reverses Train
+func TestUntrain(t *testing.T) {
+	model := NewModel("untrain")
+	model.Train(NewObservationFromText([]string{"a"}, "some text"))
+	expected := model.Copy()
+
+	mislabeled := &Observation{Classes: []string{"a", "b"}, WordCounts: map[string]int{"text": 1, "other": 3}, Weight: 0.25}
+	model.Train(mislabeled)
+	if err := model.Untrain(mislabeled); err != nil {
+		t.Fatalf("Failed to untrain observation: %v", err)
+	}
+	model.Metadata = expected.Metadata
+	if !reflect.DeepEqual(model, expected) {
+		t.Errorf("Untrain did not restore the model. Expected: %v, Got: %v", expected, model)
+	}
+
+	unseen := &Observation{Classes: []string{"a"}, WordCounts: map[string]int{"text": 2}}
+	if err := model.Untrain(unseen); err == nil {
+		t.Error("Untraining words the model has not seen did not return an error")
+	}
+	if model.Classes["a"].WordCounts["text"] != 1 {
+		t.Errorf("Failed Untrain changed the model: %v", model.Classes["a"])
+	}
+	if err := model.Untrain(NewObservationFromText([]string{"missing"}, "text")); err == nil {
+		t.Error("Untraining an unknown class did not return an error")
+	}
+}
+
+// TestIntegerModelFile tests that model files written with integer counts still load
+func TestIntegerModelFile(t *testing.T) {
+	model := &Model{}
+	loadErr := LoadFromFile("test_files/models/test_model.json", model, json.Unmarshal)
+	if loadErr != nil {
+		t.Fatalf("Failed to load integer model: %v", loadErr)
+	}
+	if model.ObservationCount != 2 || model.Classes["class_a"].WordCounts["a"] != 4 || model.Validate() != nil {
+		t.Errorf("Integer counts were not loaded. Got: %v", model)
+	}
+}
+
+func TestUntrainModel(t *testing.T) {
+	// setup
+	untrainModel := NewModel("untrain_model")
+	untrainModel.Train(NewObservationFromText([]string{"class_a"}, "a a test"))
+	untrainModelJSON, _ := json.Marshal(untrainModel)
+	createRequest, createRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model", bytes.NewBuffer(untrainModelJSON))
+	if createRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", createRequestErr)
+	}
+	http.DefaultClient.Do(createRequest)
+
+	observationJSON := []byte(`{"Classes": ["class_b"], "WordCounts": {"b": 1}, "Weight": 3}`)
+	trainRequest, trainRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/untrain_model/train", bytes.NewBuffer(observationJSON))
+	if trainRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", trainRequestErr)
+	}
+	trained := &Model{}
+	_ = unmarshalJSONResponse(t, trainRequest, http.StatusOK, trained)
+	if trained.Classes["class_b"] == nil || trained.Classes["class_b"].ObservationCount != 3 {
+		t.Errorf("Weighted observation was not trained. Got: %v", trained)
+	}
+
+	untrainRequest, untrainRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/untrain_model/untrain", bytes.NewBuffer(observationJSON))
+	if untrainRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", untrainRequestErr)
+	}
+	untrained := &Model{}
+	_ = unmarshalJSONResponse(t, untrainRequest, http.StatusOK, untrained)
+	if _, ok := untrained.Classes["class_b"]; ok || untrained.ObservationCount != 1 {
+		t.Errorf("Observation was not untrained. Got: %v", untrained)
+	}
+
+	repeatRequest, repeatRequestErr := http.NewRequest(http.MethodPost, server.URL+"/model/untrain_model/untrain", bytes.NewBuffer(observationJSON))
+	if repeatRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", repeatRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, repeatRequest, http.StatusBadRequest, &Model{})
+
+	cleanupModel(t, "untrain_model")
+}
@@ -0,0 +1,132 @@
+package naivebayes
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// Word rankings accepted by Model.ClassWords.
+const (
+	RankByFrequency   = "frequency"
+	RankByProbability = "probability"
+	RankByLogOdds     = "log_odds"
+)
+
+// WordScore struct.
+// The statistics of a word within a class.
+// LogOdds compares the word's probability in the class to its probability
+// in all the other classes combined.
+type WordScore struct {
+	Word        string
+	Count       float64
+	Probability float64
+	LogOdds     float64
+}
+
+// WordPage struct.
+// One page of ranked words, with the total number of words that can be paged through.
+type WordPage struct {
+	Words []WordScore
+	Total int
+}
+
+/*
+	ClassWords ranks the words seen in the given class.
+	rankBy is RankByFrequency (raw counts), RankByProbability (smoothed P( word | class ))
+	or RankByLogOdds (log P( word | class ) - log P( word | other classes )).
+	Words with equal scores are ordered alphabetically.
+*/
+func (m *Model) ClassWords(className string, rankBy string) (scores []WordScore, err error) {
+	class, ok := m.Classes[className]
+	if !ok {
+		return nil, fmt.Errorf("Class not found: %s", className)
+	}
+
+	// pool every other class into a single complement class
+	other := NewClass("")
+	for _, c := range m.Classes {
+		if c.Name == className {
+			continue
+		}
+		for word, count := range c.WordCounts {
+			other.addWord(word, count)
+		}
+	}
+
+	scores = []WordScore{}
+	for word, count := range class.WordCounts {
+		probability := m.wordLogProbability(class, word)
+		scores = append(scores, WordScore{Word: word, Count: count, Probability: math.Exp(probability), LogOdds: probability - m.wordLogProbability(other, word)})
+	}
+
+	var score func(s WordScore) float64
+	switch rankBy {
+	case RankByFrequency:
+		score = func(s WordScore) float64 { return s.Count }
+	case RankByProbability:
+		score = func(s WordScore) float64 { return s.Probability }
+	case RankByLogOdds:
+		score = func(s WordScore) float64 { return s.LogOdds }
+	default:
+		return nil, fmt.Errorf("Invalid rank: %s", rankBy)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if score(scores[i]) != score(scores[j]) {
+			return score(scores[i]) > score(scores[j])
+		}
+		return scores[i].Word < scores[j].Word
+	})
+	return scores, nil
+}
+
+/*
+   ENDPOINT HANDLERS
+*/
+
+/*
+   classWords displays the words of a class, ranked and paged.
+   * GET /model/<name>/class/<class>/words - The 50 most frequent words of the class
+   * GET /model/<name>/class/<class>/words?rank=frequency|probability|log_odds&offset=<o>&limit=<n>
+*/
+func (app *NaiveBayesApp) classWords(request *JSONRequest) *JSONResponse {
+	modelName := request.PathVar("modelName")
+	model, ok := app.getModel(modelName)
+
+	if !ok {
+		return &JSONResponse{Error: fmt.Errorf("Model not found"), Code: http.StatusNotFound}
+	}
+
+	rankBy := RankByFrequency
+	if rank := request.Param("rank"); rank != nil {
+		rankBy = rank[0]
+	}
+	offset, offsetErr := request.IntParam("offset", 0)
+	limit, limitErr := request.IntParam("limit", 50)
+	if offsetErr != nil || limitErr != nil || offset < 0 || limit < 1 {
+		return &JSONResponse{Error: fmt.Errorf("Invalid offset or limit"), Code: http.StatusBadRequest}
+	}
+
+	app.mu.RLock()
+	if _, ok := model.Classes[request.PathVar("className")]; !ok {
+		app.mu.RUnlock()
+		return &JSONResponse{Error: fmt.Errorf("Class not found"), Code: http.StatusNotFound}
+	}
+	scores, err := model.ClassWords(request.PathVar("className"), rankBy)
+	app.mu.RUnlock()
+	if err != nil {
+		return &JSONResponse{Error: err, Code: http.StatusBadRequest}
+	}
+
+	page := &WordPage{Words: []WordScore{}, Total: len(scores)}
+	if offset < len(scores) {
+		// compare before adding, a huge limit would overflow offset + limit
+		end := len(scores)
+		if limit < len(scores)-offset {
+			end = offset + limit
+		}
+		page.Words = scores[offset:end]
+	}
+	return &JSONResponse{Data: page, Code: http.StatusOK}
+}
@@ -0,0 +1,58 @@
+package naivebayes
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestClassWords tests ranking the words of a class
+func TestClassWords(t *testing.T) {
+	model := NewModel("words")
+	model.Train(NewObservationFromText([]string{"China"}, "Chinese Beijing Chinese the the the"))
+	model.Train(NewObservationFromText([]string{"NotChina"}, "Tokyo Japan the the the"))
+
+	frequency, _ := model.ClassWords("China", RankByFrequency)
+	if frequency[0].Word != "the" || frequency[0].Count != 3 || frequency[1].Word != "Chinese" {
+		t.Errorf("Did not rank words by frequency. Got: %v", frequency)
+	}
+
+	logOdds, _ := model.ClassWords("China", RankByLogOdds)
+	if logOdds[0].Word != "Chinese" || logOdds[len(logOdds)-1].Word != "the" {
+		t.Errorf("Did not rank words by log odds. Got: %v", logOdds)
+	}
+
+	if _, err := model.ClassWords("Japan", RankByFrequency); err == nil {
+		t.Error("Missing class did not return an error")
+	}
+	if _, err := model.ClassWords("China", "length"); err == nil {
+		t.Error("Invalid rank did not return an error")
+	}
+}
+
+func TestClassWordsEndpoint(t *testing.T) {
+	wordsRequest, wordsRequestErr := http.NewRequest(http.MethodGet, server.URL+"/model/test_model/class/class_a/words?rank=log_odds&offset=1&limit=1", nil)
+	if wordsRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", wordsRequestErr)
+	}
+	page := &WordPage{}
+	_ = unmarshalJSONResponse(t, wordsRequest, http.StatusOK, page)
+	if page.Total != 3 || len(page.Words) != 1 || page.Words[0].Word != "test" {
+		t.Errorf("Did not receive expected page of words. Got: %v", page)
+	}
+
+	largeRequest, largeRequestErr := http.NewRequest(http.MethodGet, server.URL+"/model/test_model/class/class_a/words?offset=1&limit=9223372036854775807", nil)
+	if largeRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", largeRequestErr)
+	}
+	largePage := &WordPage{}
+	_ = unmarshalJSONResponse(t, largeRequest, http.StatusOK, largePage)
+	if largePage.Total != 3 || len(largePage.Words) != 2 {
+		t.Errorf("Did not receive the rest of the words for a large limit. Got: %v", largePage)
+	}
+
+	missingRequest, missingRequestErr := http.NewRequest(http.MethodGet, server.URL+"/model/test_model/class/class_c/words", nil)
+	if missingRequestErr != nil {
+		t.Errorf("Failed to generate request: %v", missingRequestErr)
+	}
+	_ = unmarshalJSONResponse(t, missingRequest, http.StatusNotFound, &WordPage{})
+}